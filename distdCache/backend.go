@@ -0,0 +1,56 @@
+package distdCache
+
+import (
+	"time"
+)
+
+// 存储后端，用于保存标准数据（每个 bucket 一个 hash）、发布和订阅数据变更消息，以及实现分布式锁。
+// 默认使用 redis（NewRedisBackend），单元测试或者单节点部署时可以使用进程内的 MemoryBackend。
+type Backend interface {
+	// 检查后端是否可用
+	Ping() error
+	// 获取 key 的类型：none 表示不存在，hash 表示是 hash
+	Type(key string) (string, error)
+	// 获取匹配 pattern（支持 * 通配符）的所有 key
+	Keys(pattern string) ([]string, error)
+	// 删除 key
+	Del(keys ...string) error
+	// 设置 key 的过期时间
+	Expire(key string, ttl time.Duration) error
+	// 获取 hash 中的所有字段，key 不存在时返回空的 map
+	HGetAll(key string) (map[string]string, error)
+	// 获取 hash 中的一个字段，字段不存在时返回 false
+	HGet(key, field string) (string, bool, error)
+	// 设置 hash 中的一个字段
+	HSet(key, field, value string) error
+	// 删除 hash 中的字段
+	HDel(key string, fields ...string) error
+	// 在一个事务（redis 的 MULTI）中批量修改多个 hash。设置了 VersionKey 的修改只对版本号更新的字段生效，没有修改的字段及其当前版本号写入 HashBatch.Conflicts
+	ExecBatch(batches []*HashBatch) error
+	// 发布消息
+	Publish(channel, message string) error
+	// 订阅消息。收到消息时调用 onMessage，接收消息出错时调用 onError
+	Subscribe(channel string, onMessage func(message string), onError func(err error)) error
+	// 加锁。如果在超时时间内获得了锁，则返回 true，否则返回 false。 timeout<=0 表示加锁失败时立即返回、不等待。
+	Lock(key string, ttl, timeout time.Duration) (bool, error)
+	// 释放锁
+	Unlock(key string) error
+}
+
+// 对一个 hash 的批量修改
+type HashBatch struct {
+	Key    string            // hash 的 key
+	Set    map[string]string // 要设置的字段
+	Del    []string          // 要删除的字段
+	Expire time.Duration     // 修改之后设置的过期时间，0 表示不设置
+
+	VersionKey string           // 保存各字段版本号的 hash，不为空时只修改已有版本号小于 Version 的字段（Set 和 Del 都会记录版本号）
+	Version    int64            // 本次修改的版本号
+	Tombstone  time.Duration    // 已删除字段的版本号的保留时长（按版本号计算），超过之后清除，以免版本号无限增长。0 表示一直保留
+	Conflicts  map[string]int64 // 执行之后由后端填写：由于已有相同或者更新的版本而没有修改的字段，以及存储后端中该字段当前的版本号
+}
+
+// 记录已删除字段及其版本号的 key，用于清除过期的版本号
+func getDeletedVersionKey(versionKey string) string {
+	return versionKey + ":Deleted"
+}
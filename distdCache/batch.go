@@ -0,0 +1,216 @@
+package distdCache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"yelo/go-util/convertor"
+	"yelo/go-util/jsonUtil"
+)
+
+// 批量操作中对一个 key 的修改
+type editItem struct {
+	opr string
+	key string
+	val *CacheEntity
+}
+
+func (this *cacheImpl) GetMulti(keys []string) map[string]CacheEntity {
+	dict := make(map[string]CacheEntity, len(keys))
+	for index, keys := range this.groupKeysByBucket(keys) {
+		bucket := this.buckets[index]
+		bucket.lock.RLock()
+		for _, key := range keys {
+			if val, ok := bucket.data[key]; ok && val.Data != nil {
+				dict[key] = *val
+			}
+		}
+		bucket.lock.RUnlock()
+	}
+	return dict
+}
+
+func (this *cacheImpl) SetMulti(values map[string]interface{}) error {
+	nowMs := this.manager.clock.Now()
+	items := make([]*editItem, 0, len(values))
+	for key, value := range values {
+		if value == nil {
+			items = append(items, &editItem{opr: Operator_Del, key: key, val: &CacheEntity{Time: nowMs}})
+			continue
+		}
+		if _, ok := value.(string); !ok && this.newEntityFunc == nil {
+			value = convertor.ToStringNoError(value)
+		}
+		items = append(items, &editItem{opr: Operator_Set, key: key, val: &CacheEntity{Data: value, Time: nowMs}})
+	}
+	return this.doEditMulti(items, this.manager.clientId)
+}
+
+func (this *cacheImpl) DelMulti(keys []string) error {
+	nowMs := this.manager.clock.Now()
+	items := make([]*editItem, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, &editItem{opr: Operator_Del, key: key, val: &CacheEntity{Time: nowMs}})
+	}
+	return this.doEditMulti(items, this.manager.clientId)
+}
+
+// 批量修改数据，与 doEdit 相同，但是同一个 bucket 中的修改会一次完成
+func (this *cacheImpl) doEditMulti(items []*editItem, source string) error {
+	if !this.started {
+		return fmt.Errorf("请先调用 Start 方法启动缓存")
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	groups := make(map[int][]*editItem)
+	for _, item := range items {
+		index := this.getBucketIndexByKey(item.key)
+		groups[index] = append(groups[index], item)
+	}
+
+	// 如果是通过调用 SetMulti/DelMulti 接口触发的，在一个事务中写入 Redis 并发送一条广播消息
+	var conflictErr error
+	if source == this.manager.clientId {
+		for _, item := range items {
+			if item.opr == Operator_Set {
+				var err error
+				if item.val.encoded, err = this.encode(item.val.Data); err != nil {
+					return fmt.Errorf("数据编码失败: %v, key=%v", err, item.key)
+				}
+			}
+		}
+
+		// 与 doEdit 相同，redis 中已经有相同或者更大版本号的 key 推进时钟之后使用更大的版本号重试
+		pending := groups
+		for retry := 0; len(pending) != 0; retry++ {
+			batches := make([]*HashBatch, 0, len(pending))
+			for index, group := range pending {
+				batch := &HashBatch{
+					Key:        this.getBucketDataKey(index),
+					Set:        make(map[string]string),
+					Expire:     this.opt.Expire,
+					VersionKey: this.getBucketVersionKey(index),
+					Version:    group[0].val.Time,
+					Tombstone:  this.versionTombstone(),
+				}
+				for _, item := range group {
+					if item.opr == Operator_Set {
+						batch.Set[item.key] = this.marshalStored(item.val)
+					} else {
+						batch.Del = append(batch.Del, item.key)
+					}
+				}
+				batches = append(batches, batch)
+			}
+			if err := this.manager.backend.ExecBatch(batches); err != nil {
+				return fmt.Errorf("写入 redis 失败: %v", err)
+			}
+
+			conflicts := make(map[string]int64)
+			for _, batch := range batches {
+				for key, current := range batch.Conflicts {
+					conflicts[key] = current
+					this.manager.clock.Update(current)
+				}
+			}
+			if len(conflicts) == 0 {
+				break
+			} else if retry >= maxVersionRetry {
+				// 多次重试之后仍然冲突的 key 不发送消息也不修改本地数据
+				keys := make([]string, 0, len(conflicts))
+				for key := range conflicts {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				conflictErr = fmt.Errorf("%w: %v", ErrVersionConflict, strings.Join(keys, ","))
+				for index, group := range groups {
+					filtered := group[:0]
+					for _, item := range group {
+						if _, ok := conflicts[item.key]; !ok {
+							filtered = append(filtered, item)
+						}
+					}
+					groups[index] = filtered
+				}
+				break
+			}
+			nowMs := this.manager.clock.Now()
+			next := make(map[int][]*editItem)
+			for index, group := range pending {
+				for _, item := range group {
+					if _, ok := conflicts[item.key]; ok {
+						item.val.Time = nowMs
+						next[index] = append(next[index], item)
+					}
+				}
+			}
+			pending = next
+		}
+
+		// 重试过的 key 版本号不同，消息中只记录与 msg.Time 不同的版本号
+		msg := &msgQueueData{
+			ClientId: this.manager.clientId,
+			Name:     this.name,
+			Opr:      msgOperatorMulti,
+			Items:    make([]*msgQueueItem, 0, len(items)),
+		}
+		for _, group := range groups {
+			for _, item := range group {
+				if item.val.Time > msg.Time {
+					msg.Time = item.val.Time
+				}
+			}
+		}
+		for _, group := range groups {
+			for _, item := range group {
+				msgItem := &msgQueueItem{Opr: item.opr, Key: item.key, Val: item.val.encoded}
+				if item.val.Time != msg.Time {
+					msgItem.Time = item.val.Time
+				}
+				msg.Items = append(msg.Items, msgItem)
+			}
+		}
+		if len(msg.Items) == 0 {
+			return conflictErr
+		}
+		if err := this.manager.backend.Publish(msgQueueChannel, jsonUtil.MustMarshalToString(msg)); err != nil {
+			// 与 doEdit 相同，ETag 同步机制可自动纠正
+			this.manager.opt.Logger.Warn("publish msg error: %v", err)
+		}
+	}
+
+	// 按 bucket 更新本地数据
+	for index, group := range groups {
+		bucket := this.buckets[index]
+		changes := make([]*editItem, 0, len(group))
+		bucket.lock.Lock()
+		for _, item := range group {
+			// 通过比较 Time 过滤掉旧版本的消息，以及写入 redis 期间已经被更新版本覆盖的本地修改
+			if localVal := bucket.data[item.key]; localVal != nil && item.val.Time < localVal.Time {
+				continue
+			}
+			if changed, data := this.updateLocal(bucket, item.opr, item.key, item.val); changed {
+				changes = append(changes, &editItem{opr: item.opr, key: item.key, val: &CacheEntity{Data: data, Time: item.val.Time}})
+			}
+		}
+		bucket.lock.Unlock()
+
+		// fire event
+		for _, item := range changes {
+			this.notifyChange(item.opr, item.key, *item.val, source)
+		}
+	}
+
+	return conflictErr
+}
+
+func (this *cacheImpl) groupKeysByBucket(keys []string) map[int][]string {
+	groups := make(map[int][]string)
+	for _, key := range keys {
+		index := this.getBucketIndexByKey(key)
+		groups[index] = append(groups[index], key)
+	}
+	return groups
+}
@@ -0,0 +1,74 @@
+package distdCache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_Multi(t *testing.T) {
+	var lock sync.Mutex
+	changes := make(map[string]int)
+	onChange := func(opr, key string, val CacheEntity, source string) {
+		lock.Lock()
+		defer lock.Unlock()
+		changes[source+" "+opr]++
+	}
+	backend := NewMemoryBackend()
+	var messages int32
+	backend.Subscribe(msgQueueChannel, func(message string) { atomic.AddInt32(&messages, 1) }, nil)
+	caches := newTestCluster(t, backend, "test-multi", 2, &CacheOption{BucketCount: 8, OnChange: onChange})
+	source := caches[0].Manager().ClientId()
+
+	values := make(map[string]interface{})
+	keys := make([]string, 0)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("k%d", i)
+		values[key], keys = i, append(keys, key)
+	}
+	if err := caches[0].SetMulti(values); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return len(caches[1].GetMulti(keys)) == 1000 }) {
+		t.Errorf("assert faild: %v", len(caches[1].GetMulti(keys)))
+	}
+	if v := caches[1].GetMulti([]string{"k1", "k999", "none"}); len(v) != 2 || v["k1"].Data != "1" || v["k999"].Data != "999" {
+		t.Errorf("assert faild: %v", v)
+	}
+	if n := atomic.LoadInt32(&messages); n != 1 {
+		t.Errorf("should publish one message, got %v", n)
+	}
+
+	// 批量删除，以及 SetMulti 中值为 nil 表示删除
+	if err := caches[1].DelMulti(keys[:500]); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if err := caches[1].SetMulti(map[string]interface{}{"k500": nil, "k501": "new"}); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool {
+		return len(caches[0].GetMulti(keys)) == 499 && caches[0].GetData("k501") == "new"
+	}) {
+		t.Errorf("assert faild: %v", len(caches[0].GetMulti(keys)))
+	}
+
+	// 存储后端中的数据与本地一致
+	late := newTestCluster(t, backend, "test-multi", 1, &CacheOption{BucketCount: 8})[0]
+	if v := late.GetAll(); len(v) != 499 || v["k501"].Data != "new" {
+		t.Errorf("assert faild: %v", len(v))
+	}
+
+	// 每个 key 都触发 OnChange（OnChange 是异步触发的，需要等待两个节点都处理完毕）
+	other := caches[1].Manager().ClientId()
+	if !waitFor(time.Second, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return changes[source+" set"] == 2000 && changes[source+" del"] == 0 && changes[other+" del"] == 1002 && changes[other+" set"] == 2
+	}) {
+		lock.Lock()
+		t.Errorf("assert faild: %v", changes)
+		lock.Unlock()
+	}
+}
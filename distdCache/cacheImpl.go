@@ -2,11 +2,10 @@ package distdCache
 
 import (
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
-	"github.com/go-redis/redis"
 	"hash/crc32"
 	"os"
-	"reflect"
 	"runtime/debug"
 	"sort"
 	"strconv"
@@ -19,15 +18,17 @@ import (
 )
 
 type cacheImpl struct {
-	manager         *cacheManagerImpl  //
-	opt             *CacheOption       //
-	name            string             //
-	newEntityFunc   func() interface{} //
-	bucketKeyPrefix string             //
-	lockNamePrefix  string             //
-	syncLockName    string             //
-	buckets         []*bucket          //
-	started         bool               //
+	manager         *cacheManagerImpl    //
+	opt             *CacheOption         //
+	name            string               //
+	newEntityFunc   func() interface{}   //
+	bucketKeyPrefix string               //
+	lockNamePrefix  string               //
+	syncLockName    string               //
+	buckets         []*bucket            //
+	started         bool                 //
+	loading         map[string]*loadCall // 正在加载的 key
+	loadingLock     sync.Mutex           //
 }
 
 type bucket struct {
@@ -71,8 +72,8 @@ func (this *cacheImpl) Start() error {
 		}
 		this.started = true
 
-		if tmp := this.manager.redisClient.Type(this.bucketKeyPrefix + ":ETag").Val(); tmp != "none" && tmp != "hash" {
-			this.manager.redisClient.Del(this.bucketKeyPrefix + ":ETag")
+		if tmp, _ := this.manager.backend.Type(this.bucketKeyPrefix + ":ETag"); tmp != "" && tmp != "none" && tmp != "hash" {
+			this.manager.backend.Del(this.bucketKeyPrefix + ":ETag")
 		}
 
 		if err := this.ForceSync(); err != nil {
@@ -130,14 +131,18 @@ func (this *cacheImpl) GetData(key string) interface{} {
 
 func (this *cacheImpl) Set(key string, value interface{}) error {
 	if value != nil {
-		return this.doEdit(Operator_Set, key, &CacheEntity{Data: value, Time: timeUtil.ToMs(time.Now())}, this.manager.clientId)
+		if _, ok := value.(string); !ok && this.newEntityFunc == nil {
+			// 没有指定 newEntityFunc 时数据为字符串，本地与其他节点保持一致
+			value = convertor.ToStringNoError(value)
+		}
+		return this.doEdit(Operator_Set, key, &CacheEntity{Data: value, Time: this.manager.clock.Now()}, this.manager.clientId)
 	} else {
-		return this.doEdit(Operator_Del, key, &CacheEntity{Time: timeUtil.ToMs(time.Now())}, this.manager.clientId)
+		return this.doEdit(Operator_Del, key, &CacheEntity{Time: this.manager.clock.Now()}, this.manager.clientId)
 	}
 }
 
 func (this *cacheImpl) Del(key string) error {
-	return this.doEdit(Operator_Del, key, &CacheEntity{Time: timeUtil.ToMs(time.Now())}, this.manager.clientId)
+	return this.doEdit(Operator_Del, key, &CacheEntity{Time: this.manager.clock.Now()}, this.manager.clientId)
 }
 
 func (this *cacheImpl) ForceSync() error {
@@ -145,11 +150,11 @@ func (this *cacheImpl) ForceSync() error {
 		return this.Start()
 	}
 
-	locked, err := this.manager.redisLock.Lock(this.syncLockName, this.manager.opt.SyncCheckInterval, 3*time.Second)
+	locked, err := this.manager.backend.Lock(this.syncLockName, this.manager.opt.SyncCheckInterval, 3*time.Second)
 	if err != nil {
 		return err
 	} else if locked {
-		defer this.manager.redisLock.Unlock(this.syncLockName)
+		defer this.manager.backend.Unlock(this.syncLockName)
 	}
 
 	for i := range this.buckets {
@@ -165,16 +170,21 @@ func (this *cacheImpl) Clear() error {
 		return fmt.Errorf("请先调用 Start 方法启动缓存")
 	}
 
-	// 删除 redis 中的 Data
-	keys, err := this.manager.redisClient.Keys(this.bucketKeyPrefix + ":Data:*").Result()
-	if len(keys) != 0 {
-		err = this.manager.redisClient.Del(keys...).Err()
+	// 删除 redis 中的 Data 和 Version
+	keys, err := this.manager.backend.Keys(this.bucketKeyPrefix + ":Data:*")
+	if err == nil {
+		var versionKeys []string
+		versionKeys, err = this.manager.backend.Keys(this.bucketKeyPrefix + ":Version:*")
+		keys = append(keys, versionKeys...)
+	}
+	if err == nil && len(keys) != 0 {
+		err = this.manager.backend.Del(keys...)
 	}
-	if err != nil && err != redis.Nil {
+	if err != nil {
 		return fmt.Errorf("清空缓存数据失败: %v", err)
 	}
 	// 删除 redis 中的 ETag
-	this.manager.redisClient.Del(this.bucketKeyPrefix + ":ETag")
+	this.manager.backend.Del(this.bucketKeyPrefix + ":ETag")
 
 	// 立即同步
 	for i := range this.buckets {
@@ -187,16 +197,89 @@ func (this *cacheImpl) Clear() error {
 	return nil
 }
 
-func (this *cacheImpl) newEntity(s string) interface{} {
+// 使用默认的 JsonCodec 并且不压缩时，存储、变更消息以及 ETag 都使用与旧版本相同的格式，以便新旧版本的节点可以同时运行（滚动升级）
+func (this *cacheImpl) legacyFormat() bool {
+	return this.opt.Codec == JsonCodec && this.opt.CompressThreshold <= 0
+}
+
+// 使用 Codec 编码数据，用于存储、发送变更消息以及计算 ETag
+func (this *cacheImpl) encode(v interface{}) (string, error) {
+	if this.legacyFormat() {
+		// 旧版本的格式：字符串原样保存，其他类型为 Json
+		return convertor.ToString(v)
+	}
+	return encodeData(this.opt.Codec, v, this.opt.CompressThreshold)
+}
+
+// 解码数据。如果构造缓存实例时指定了 newEntityFunc，则返回该函数创建的实例；否则返回字符串
+func (this *cacheImpl) decode(s string) (interface{}, error) {
+	if this.legacyFormat() {
+		if this.newEntityFunc == nil {
+			return s, nil
+		}
+		v := this.newEntityFunc()
+		err := jsonUtil.UnmarshalFromString(s, v)
+		return v, err
+	}
+	if this.newEntityFunc == nil {
+		var str string
+		err := decodeData(this.opt.Codec, s, &str)
+		return str, err
+	}
+	v := this.newEntityFunc()
+	err := decodeData(this.opt.Codec, s, v)
+	return v, err
+}
+
+// 数据在存储后端中的格式：{Time}|{编码之后的数据}；使用旧版本格式时为 Json：{"data":...,"time":...}
+func (this *cacheImpl) marshalStored(val *CacheEntity) string {
+	if this.legacyFormat() {
+		return convertor.ToStringNoError(&CacheEntity{Data: val.Data, Time: val.Time})
+	}
+	return strconv.FormatInt(val.Time, 10) + "|" + val.encoded
+}
+
+// 解析存储后端中的数据，只解析 Time，Data 由调用者在需要时解码
+func (this *cacheImpl) unmarshalStored(s string) (*CacheEntity, error) {
+	if strings.HasPrefix(s, "{") {
+		return this.unmarshalLegacy(s)
+	}
+	pos := strings.IndexByte(s, '|')
+	if pos == -1 {
+		return nil, fmt.Errorf("数据格式不正确: %v", s)
+	}
+	t, err := strconv.ParseInt(s[:pos], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("数据格式不正确: %v", s)
+	}
+	return &CacheEntity{Time: t, encoded: s[pos+1:]}, nil
+}
+
+// 兼容旧版本以 Json 格式保存的数据：{"data":...,"time":...}
+func (this *cacheImpl) unmarshalLegacy(s string) (*CacheEntity, error) {
+	legacy := &struct {
+		Data json.RawMessage `json:"data"`
+		Time int64           `json:"time"`
+	}{}
+	if err := jsonUtil.UnmarshalFromString(s, legacy); err != nil {
+		return nil, err
+	}
+	val := &CacheEntity{Time: legacy.Time}
 	if this.newEntityFunc == nil {
-		return s
+		var v interface{}
+		if err := jsonUtil.Unmarshal(legacy.Data, &v); err != nil {
+			return nil, err
+		}
+		val.Data = convertor.ToStringNoError(v)
 	} else {
-		v := this.newEntityFunc()
-		if s != "" {
-			jsonUtil.UnmarshalFromString(s, v)
+		val.Data = this.newEntityFunc()
+		if err := jsonUtil.Unmarshal(legacy.Data, val.Data); err != nil {
+			return nil, err
 		}
-		return v
 	}
+	var err error
+	val.encoded, err = this.encode(val.Data)
+	return val, err
 }
 
 // 参数:
@@ -232,68 +315,104 @@ func (this *cacheImpl) doEdit(opr, key string, val *CacheEntity, source string)
 
 	// 如果是通过调用 Set/Del 接口触发的，将数据写入 Redis 并发送广播消息
 	if source == this.manager.clientId {
-		// 写入 redis。
+		// 按版本号写入 redis。
 		var err error
 		if opr == Operator_Set {
-			err = this.manager.redisClient.HSet(redisKey, key, jsonUtil.MustMarshalToString(val)).Err()
-		} else {
-			err = this.manager.redisClient.HDel(redisKey, key).Err()
-		}
-		if err != nil && err != redis.Nil {
-			return fmt.Errorf("写入 redis 失败: %v", err)
+			if val.encoded, err = this.encode(val.Data); err != nil {
+				return fmt.Errorf("数据编码失败: %v", err)
+			}
 		}
-		if this.opt.Expire > 0 {
-			this.manager.redisClient.Expire(redisKey, this.opt.Expire)
+		for retry := 0; ; retry++ {
+			batch := &HashBatch{Key: redisKey, Expire: this.opt.Expire, VersionKey: this.getBucketVersionKey(index), Version: val.Time, Tombstone: this.versionTombstone()}
+			if opr == Operator_Set {
+				batch.Set = map[string]string{key: this.marshalStored(val)}
+			} else {
+				batch.Del = []string{key}
+			}
+			if err = this.manager.backend.ExecBatch([]*HashBatch{batch}); err != nil {
+				return fmt.Errorf("写入 redis 失败: %v", err)
+			}
+			current, ok := batch.Conflicts[key]
+			if !ok {
+				break
+			} else if retry >= maxVersionRetry {
+				return ErrVersionConflict
+			}
+			// redis 中已经有相同或者更大的版本号（其他节点的时间较快，或者还没有收到其变更消息），推进时钟之后使用更大的版本号重试，
+			// 使后写入 redis 的修改总是生效，而不依赖各节点的时间
+			this.manager.clock.Update(current)
+			val.Time = this.manager.clock.Now()
 		}
 
 		// 发布消息
-		err = this.manager.redisClient.Publish(msgQueueChannel, jsonUtil.MustMarshalToString(&msgQueueData{
+		err = this.manager.backend.Publish(msgQueueChannel, jsonUtil.MustMarshalToString(&msgQueueData{
 			ClientId: this.manager.clientId,
 			Name:     this.name,
 			Opr:      opr,
 			Key:      key,
-			Val:      convertor.ToStringNoError(val.Data),
+			Val:      val.encoded,
 			Time:     val.Time,
-		})).Err()
+		}))
 		if err != nil {
 			// 此处只记日志但不返回，因为前面写 Redis 如果没有出错，那么此处极大概率此处也不会出错，况且即使出错也不需要特别处理，ETag 同步机制可自动纠正
 			this.manager.opt.Logger.Warn("publish msg error: %v", err)
 		}
 	}
 
-	var changed bool
-	var changeData interface{}
-
-	// 第三步：更新本地数据
+	// 第三步：更新本地数据。写入 redis 期间本地数据可能已经被更新版本的消息或者本地修改更新，需要在写锁中再次比较 Time
 	bucket.lock.Lock()
+	if localVal := bucket.data[key]; localVal != nil && val.Time < localVal.Time {
+		bucket.lock.Unlock()
+		return nil
+	}
+	changed, changeData := this.updateLocal(bucket, opr, key, val)
+	bucket.lock.Unlock()
+
+	// fire event
+	if changed {
+		this.notifyChange(opr, key, CacheEntity{Data: changeData, Time: val.Time}, source)
+	}
+
+	return nil
+}
+
+// 更新本地数据，返回数据是否发生了变化以及用于通知的数据（del 时为删除之前的数据）
+func (this *cacheImpl) updateLocal(bucket *bucket, opr, key string, val *CacheEntity) (bool, interface{}) {
+	/**
+	!!! 注意：此函数内部不要对 bucket 加锁，调用者已经加锁。里面再加锁就会死锁
+	*/
+	localVal, nowMs := bucket.data[key], timeUtil.ToMs(time.Now())
 	if opr == Operator_Set {
 		if localVal == nil {
-			changed, changeData = true, val.Data
-			bucket.data[key] = &CacheEntity{Data: val.Data, Time: val.Time}
-		} else if !reflect.DeepEqual(localVal.Data, val.Data) {
-			changed, changeData = true, val.Data
-			localVal.Data, localVal.Time = val.Data, val.Time
+			bucket.data[key] = &CacheEntity{Data: val.Data, Time: val.Time, encoded: val.encoded, localTime: nowMs}
+			return true, val.Data
+		} else if localVal.Data == nil || localVal.encoded != val.encoded {
+			localVal.Data, localVal.Time, localVal.encoded, localVal.localTime = val.Data, val.Time, val.encoded, nowMs
+			return true, val.Data
 		}
-	} else {
-		if localVal != nil && localVal.Data != nil {
-			changed, changeData = true, localVal.Data
-			localVal.Data, localVal.Time = nil, val.Time
+		// 数据没有变化，只更新版本号，保持与存储后端一致
+		if localVal.Time != val.Time {
+			localVal.Time, localVal.localTime = val.Time, nowMs
 		}
+	} else if localVal != nil && localVal.Data != nil {
+		data := localVal.Data
+		localVal.Data, localVal.Time, localVal.encoded, localVal.localTime = nil, val.Time, "", nowMs
+		return true, data
 	}
-	bucket.lock.Unlock()
+	return false, nil
+}
 
-	// fire event
-	if changed && this.opt.OnChange != nil {
+// 触发 OnChange 事件
+func (this *cacheImpl) notifyChange(opr, key string, val CacheEntity, source string) {
+	if this.opt.OnChange != nil {
 		this.manager.notifyQueue.Add(&notifyQueueData{
 			f:      this.opt.OnChange,
 			opr:    opr,
 			key:    key,
-			val:    CacheEntity{Data: changeData, Time: val.Time},
+			val:    val,
 			source: source,
 		})
 	}
-
-	return nil
 }
 
 func (this *cacheImpl) getBucketIndexByKey(key string) int {
@@ -308,12 +427,23 @@ func (this *cacheImpl) getBucketDataKey(index int) string {
 	return fmt.Sprintf("%s:Data:%02d", this.bucketKeyPrefix, index)
 }
 
+// 保存 bucket 中各个 key 的版本号的 hash。删除 key 时也会保留版本号（保留 versionTombstone），用于忽略旧版本的修改
+func (this *cacheImpl) getBucketVersionKey(index int) string {
+	return fmt.Sprintf("%s:Version:%02d", this.bucketKeyPrefix, index)
+}
+
+// 已删除的 key 的版本号在存储后端中保留的时长。
+// 本地的删除标记保留 2 个同步周期，数据不一致时最多经过 3 个同步周期即可修复，超过之后不会再有基于旧版本的修改
+func (this *cacheImpl) versionTombstone() time.Duration {
+	return 3 * this.manager.opt.SyncCheckInterval
+}
+
 func (this *cacheImpl) getETagLockName(index int) string {
 	return fmt.Sprintf("%s.ETag-%02d", this.lockNamePrefix, index)
 }
 
 func (this *cacheImpl) checkSync() error {
-	locked, err := this.manager.redisLock.Lock(this.syncLockName, this.manager.opt.SyncCheckInterval, 3*time.Second)
+	locked, err := this.manager.backend.Lock(this.syncLockName, this.manager.opt.SyncCheckInterval, 3*time.Second)
 	if err != nil {
 		return err
 	} else if !locked {
@@ -322,7 +452,7 @@ func (this *cacheImpl) checkSync() error {
 	}
 
 	defer func() {
-		this.manager.redisLock.Unlock(this.syncLockName)
+		this.manager.backend.Unlock(this.syncLockName)
 		if e := recover(); e != nil {
 			this.manager.opt.Logger.Error("[%v] distdCache.checkSync panic: %v\n", time.Now().Format("2006-01-02 15:04:05.000"), e)
 			os.Stderr.WriteString(fmt.Sprintf("[%v] distdCache.checkSync panic: %v\n", time.Now().Format("2006-01-02 15:04:05.000"), e))
@@ -367,8 +497,8 @@ func (this *cacheImpl) doSyncBucket(index int) error {
 	bucket.lock.Lock()
 	defer bucket.lock.Unlock()
 
-	serverData, err := this.manager.redisClient.HGetAll(this.getBucketDataKey(index)).Result()
-	if err != nil && err != redis.Nil {
+	serverData, err := this.manager.backend.HGetAll(this.getBucketDataKey(index))
+	if err != nil {
 		return fmt.Errorf("读取 redis 数据失败: %v", err)
 	}
 
@@ -376,7 +506,8 @@ func (this *cacheImpl) doSyncBucket(index int) error {
 	for key, localVal := range bucket.data {
 		if _, ok := serverData[key]; !ok && localVal.Data != nil {
 			// update
-			localVal.Data, localVal.Time = nil, nowMs
+			// 不知道删除时的版本号，取已知的最大版本号，忽略删除之前的延迟消息
+			localVal.Data, localVal.Time, localVal.localTime = nil, this.manager.clock.Peek(), nowMs
 			// fire event
 			if this.opt.OnChange != nil {
 				this.manager.notifyQueue.Add(&notifyQueueData{
@@ -391,47 +522,54 @@ func (this *cacheImpl) doSyncBucket(index int) error {
 	}
 	// 加载服务端的 key-value
 	for key, valStr := range serverData {
-		val := &CacheEntity{}
-		if err := jsonUtil.UnmarshalFromString(valStr, val); err != nil {
+		val, err := this.unmarshalStored(valStr)
+		if err != nil {
+			this.manager.opt.Logger.Warn("解析数据失败: %v", err)
 			continue
-		} else if val.Data != nil {
-			val.Data = this.newEntity(convertor.ToStringNoError(val.Data))
 		}
+		this.manager.clock.Update(val.Time)
 		localVal, ok := bucket.data[key]
-		if !ok || !reflect.DeepEqual(localVal.Data, val.Data) {
-			// update
-			if localVal == nil {
-				bucket.data[key] = val
-			} else {
-				localVal.Data, localVal.Time = val.Data, val.Time
+		if ok && localVal.Data != nil && localVal.encoded == val.encoded {
+			// 数据没有变化，不需要解码，只更新版本号
+			if localVal.Time != val.Time {
+				localVal.Time, localVal.localTime = val.Time, nowMs
 			}
-
-			// fire event
-			if this.opt.OnChange != nil {
-				var opr string
-				if val.Data != nil {
-					opr = Operator_Set
-				} else {
-					opr = Operator_Del
-				}
-				this.manager.notifyQueue.Add(&notifyQueueData{
-					f:      this.opt.OnChange,
-					opr:    opr,
-					key:    key,
-					val:    *val,
-					source: "sync",
-				})
+			continue
+		}
+		if val.Data == nil {
+			if val.Data, err = this.decode(val.encoded); err != nil {
+				this.manager.opt.Logger.Warn("数据解码失败: %v, key=%v", err, key)
+				continue
 			}
 		}
+
+		// update
+		val.localTime = nowMs
+		if localVal == nil {
+			bucket.data[key] = val
+		} else {
+			localVal.Data, localVal.Time, localVal.encoded, localVal.localTime = val.Data, val.Time, val.encoded, nowMs
+		}
+
+		// fire event
+		if this.opt.OnChange != nil {
+			this.manager.notifyQueue.Add(&notifyQueueData{
+				f:      this.opt.OnChange,
+				opr:    Operator_Set,
+				key:    key,
+				val:    *val,
+				source: "sync",
+			})
+		}
 	}
 
 	// 重新计算 ETag，并写入服务端
 	this.updateEtag(bucket, nowMs)
 	etagRedisKey := this.bucketKeyPrefix + ":ETag"
 	if bucket.etag == "" {
-		this.manager.redisClient.HDel(etagRedisKey, strconv.Itoa(index))
+		this.manager.backend.HDel(etagRedisKey, strconv.Itoa(index))
 	} else {
-		this.manager.redisClient.HSet(etagRedisKey, strconv.Itoa(index), fmt.Sprintf("%v-%v", bucket.etag, bucket.etagTime))
+		this.manager.backend.HSet(etagRedisKey, strconv.Itoa(index), fmt.Sprintf("%v-%v", bucket.etag, bucket.etagTime))
 	}
 
 	return nil
@@ -441,8 +579,8 @@ func (this *cacheImpl) getServerEtags() (map[int]*serverEtagData, error) {
 	etagRedisKey := this.bucketKeyPrefix + ":ETag"
 
 	// 获取服务端的所有 ETag
-	dict, err := this.manager.redisClient.HGetAll(etagRedisKey).Result()
-	if err != nil && err != redis.Nil {
+	dict, err := this.manager.backend.HGetAll(etagRedisKey)
+	if err != nil {
 		return nil, err
 	}
 
@@ -460,7 +598,7 @@ func (this *cacheImpl) getServerEtags() (map[int]*serverEtagData, error) {
 		}
 	}
 	if len(keysToDel) != 0 {
-		this.manager.redisClient.HDel(etagRedisKey, keysToDel...)
+		this.manager.backend.HDel(etagRedisKey, keysToDel...)
 	}
 
 	return serverETagMap, nil
@@ -475,12 +613,13 @@ func (this *cacheImpl) updateEtag(bucket *bucket, nowMs int64) bool {
 		delIfTimeBefore := nowMs - interval*2
 		arr, keysToDel := make([]string, 0, len(bucket.data)), make([]string, 0, 64)
 		for k, v := range bucket.data {
-			if v.Time > etagTime {
-				// 计算 ETag 时会按 SyncCheckInterval 取整，计算在整点之前的数据对应的 ETag。所以 >etagTime 的忽略不参与 Etag 计算
+			if v.localTime > etagTime {
+				// 计算 ETag 时会按 SyncCheckInterval 取整，计算在整点之前的数据对应的 ETag。所以 >etagTime 的忽略不参与 Etag 计算。
+				// 使用本地更新数据的时间而不是版本号判断，版本号可能因为其他节点的时间偏差而大于实际时间
 				continue
 			} else if v.Data != nil {
-				arr = append(arr, k+"="+convertor.ToStringNoError(v))
-			} else if v.Time < delIfTimeBefore {
+				arr = append(arr, k+"="+this.marshalStored(v))
+			} else if v.localTime < delIfTimeBefore {
 				keysToDel = append(keysToDel, k)
 			}
 		}
@@ -2,32 +2,31 @@ package distdCache
 
 import (
 	"fmt"
-	"github.com/go-redis/redis"
 	"strings"
 	"sync"
 	"time"
 	"yelo/go-util/jsonUtil"
-	"yelo/go-util/redisLock"
 	"yelo/go-util/taskQueue/chanTaskQueue"
 	"yelo/go-util/timeRoundedCounter"
 	"yelo/go-util/timeUtil"
 )
 
 const (
-	msgQueueChannel = "DistdCache:Channel"
+	msgQueueChannel  = "DistdCache:Channel"
+	msgOperatorMulti = "multi" // 批量操作的消息，数据保存在 Items 中
+	maxVersionRetry  = 10      // 写入存储后端时遇到版本号冲突的最大重试次数
 )
 
 type cacheManagerImpl struct {
 	clientId      string                // 客户端 ID，分布式系统中的每个客户端应该有独立的 ID。
-	redisClient   *redis.Client         //
+	backend       Backend               // 存储后端
+	clock         *versionClock         // 生成数据版本号的时钟
 	opt           *CacheManagerOptions  //
 	msgQueue      chanTaskQueue.Queue   //
 	notifyQueue   chanTaskQueue.Queue   //
-	subscriber    *redis.PubSub         // 消息订阅者
 	cacheInstance map[string]*cacheImpl //
 	checkTicker   timeUtil.Ticker       //
 	instanceLock  sync.RWMutex          //
-	redisLock     redisLock.RedisLock   //
 }
 
 type msgQueueData struct {
@@ -36,7 +35,16 @@ type msgQueueData struct {
 	Opr      string `json:"opr" description:"operator, set|del"`
 	Key      string `json:"k" description:"key"`
 	Val      string `json:"v,omitempty" description:"value"`
-	Time     int64  `json:"t" description:"消息的更新时间（ms）"`
+	Time     int64  `json:"t" description:"数据的版本号（单位与 ms 相同）"`
+
+	Items []*msgQueueItem `json:"i,omitempty" description:"批量操作的数据（opr 为 multi 时）"`
+}
+
+type msgQueueItem struct {
+	Opr  string `json:"o" description:"operator, set|del"`
+	Key  string `json:"k" description:"key"`
+	Val  string `json:"v,omitempty" description:"value"`
+	Time int64  `json:"t,omitempty" description:"数据的版本号，为 0 时与 msgQueueData.Time 相同"`
 }
 
 type notifyQueueData struct {
@@ -63,25 +71,49 @@ func (this *cacheManagerImpl) ensureStart() error {
 	if this.clientId = strings.TrimSpace(this.clientId); this.clientId == "" {
 		return fmt.Errorf("必须设置 clientId")
 	}
-	if this.redisClient == nil {
-		return fmt.Errorf("必须设置 redisClient")
+	if this.backend == nil {
+		return fmt.Errorf("必须设置 backend")
 	}
 
-	// 测试 redis
-	if err := this.redisClient.Ping().Err(); err != nil {
-		return fmt.Errorf("无法访问 redis: %v", err)
+	// 测试存储后端
+	if err := this.backend.Ping(); err != nil {
+		return fmt.Errorf("无法访问存储后端: %v", err)
 	}
 
 	// 初始化其他启动参数
 	this.msgQueue = chanTaskQueue.New("distdCacheSubscribe", this.opt.QueueCapicity, func(v interface{}, t time.Time) {
 		msg := v.(*msgQueueData)
+		this.clock.Update(msg.Time)
 		this.instanceLock.RLock()
 		cache := this.cacheInstance[msg.Name]
 		this.instanceLock.RUnlock()
-		if cache != nil && cache.started {
+		if cache != nil && cache.started && msg.Opr == msgOperatorMulti {
+			items := make([]*editItem, 0, len(msg.Items))
+			for _, item := range msg.Items {
+				val := &CacheEntity{Time: msg.Time}
+				if item.Time != 0 {
+					val.Time = item.Time
+				}
+				if item.Opr == Operator_Set {
+					data, err := cache.decode(item.Val)
+					if err != nil {
+						this.opt.Logger.Warn("数据解码失败: %v, key=%v", err, item.Key)
+						continue
+					}
+					val.Data, val.encoded = data, item.Val
+				}
+				items = append(items, &editItem{opr: item.Opr, key: item.Key, val: val})
+			}
+			cache.doEditMulti(items, msg.ClientId)
+		} else if cache != nil && cache.started {
 			val := &CacheEntity{Time: msg.Time}
 			if msg.Opr == Operator_Set {
-				val.Data = cache.newEntity(msg.Val)
+				data, err := cache.decode(msg.Val)
+				if err != nil {
+					this.opt.Logger.Warn("数据解码失败: %v, msg=%v", err, msg)
+					return
+				}
+				val.Data, val.encoded = data, msg.Val
 			}
 			cache.doEdit(msg.Opr, msg.Key, val, msg.ClientId)
 		}
@@ -125,6 +157,12 @@ func (this *cacheManagerImpl) NewCache(name string, newEntityFunc func() interfa
 		realOpt = &CacheOption{}
 	}
 
+	if realOpt.Codec == nil {
+		realOpt.Codec = JsonCodec
+	}
+	if realOpt.LoadTimeout <= 0 {
+		realOpt.LoadTimeout = 10 * time.Second
+	}
 	if realOpt.BucketCount <= 0 {
 		realOpt.BucketCount = 100
 	} else if realOpt.BucketCount > 4096 {
@@ -149,6 +187,7 @@ func (this *cacheManagerImpl) NewCache(name string, newEntityFunc func() interfa
 		bucketKeyPrefix: fmt.Sprintf("DistdCache:%s", strings.Replace(name, ":", "-", -1)),
 		syncLockName:    fmt.Sprintf("DistdCache.%s", strings.Replace(name, ":", "-", -1)),
 		buckets:         make([]*bucket, realOpt.BucketCount),
+		loading:         make(map[string]*loadCall),
 	}
 	for i := range instance.buckets {
 		instance.buckets[i] = &bucket{data: make(map[string]*CacheEntity)}
@@ -172,21 +211,13 @@ func (this *cacheManagerImpl) checkSync() {
 	}
 }
 
-// 开始消费 kafka 消息
+// 开始消费数据变更消息
 func (this *cacheManagerImpl) startMsgSubscriber() error {
-	client := redis.NewClient(this.redisClient.Options())
-	this.subscriber = client.Subscribe(msgQueueChannel)
-	go func() {
-		defer this.subscriber.Close()
-		for {
-			if msg, err := this.subscriber.ReceiveMessage(); err != nil {
-				this.opt.Logger.Error("[%v] receive error: %v", this.clientId, err)
-			} else {
-				this.consumeOneMessage(msg.Payload)
-			}
-		}
-	}()
-	return nil
+	return this.backend.Subscribe(msgQueueChannel, func(message string) {
+		this.consumeOneMessage(message)
+	}, func(err error) {
+		this.opt.Logger.Error("[%v] receive error: %v", this.clientId, err)
+	})
 }
 
 // 消费一条消息，返回该消息是否已被处理
@@ -202,7 +233,16 @@ func (this *cacheManagerImpl) consumeOneMessage(data string) error {
 		return nil
 	}
 
-	if msg.Name == "" || msg.Key == "" || msg.Time == 0 || (msg.Opr != Operator_Set && msg.Opr != Operator_Del) {
+	valid := msg.Name != "" && msg.Time != 0
+	if msg.Opr == msgOperatorMulti {
+		valid = valid && len(msg.Items) != 0
+		for _, item := range msg.Items {
+			valid = valid && item.Key != "" && (item.Opr == Operator_Set || item.Opr == Operator_Del)
+		}
+	} else {
+		valid = valid && msg.Key != "" && (msg.Opr == Operator_Set || msg.Opr == Operator_Del)
+	}
+	if !valid {
 		this.opt.Logger.Warn("消息格式不正确, msg=%v", msg)
 		return nil
 	}
@@ -0,0 +1,142 @@
+package distdCache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"unicode/utf8"
+	"yelo/go-util/jsonUtil"
+)
+
+// 缓存数据的编解码器，用于存储到后端、发送变更消息以及计算 ETag
+type Codec interface {
+	// 编码
+	Marshal(v interface{}) ([]byte, error)
+	// 解码，v 为构造缓存实例时 newEntityFunc 返回的实例；没有指定 newEntityFunc 时为 *string
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// Json 编解码器（默认），按照 key 排序序列化 map，确保同样的数据编码结果相同
+	JsonCodec Codec = &jsonCodec{}
+	// gob 编解码器，需要提前通过 gob.Register 注册接口类型字段的实际类型
+	GobCodec Codec = &gobCodec{}
+	// 原样保存 []byte 或者 string，不做任何转换
+	RawCodec Codec = &rawCodec{}
+)
+
+type jsonCodec struct{}
+
+func (this *jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return jsonUtil.SortMapKeysApi().Marshal(v)
+}
+
+func (this *jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return jsonUtil.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (this *gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (this *gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type rawCodec struct{}
+
+func (this *rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case *[]byte:
+		return *t, nil
+	case string:
+		return []byte(t), nil
+	case *string:
+		return []byte(*t), nil
+	default:
+		return nil, fmt.Errorf("RawCodec 不支持的数据类型: %T", v)
+	}
+}
+
+func (this *rawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch t := v.(type) {
+	case *[]byte:
+		*t = append([]byte(nil), data...)
+	case *string:
+		*t = string(data)
+	default:
+		return fmt.Errorf("RawCodec 不支持的数据类型: %T", v)
+	}
+	return nil
+}
+
+// 编码之后的数据格式：{格式标记}{内容}。为了能够放在 Json 格式的消息中，二进制内容使用 base64 编码
+const (
+	encodingPlain  = 'p' // 内容是编码器输出的文本
+	encodingBase64 = 'b' // 内容是编码器输出的二进制数据的 base64
+	encodingGzip   = 'z' // 内容是编码器输出经过 gzip 压缩之后的 base64
+)
+
+// 使用 Codec 编码，超过压缩阈值时压缩
+func encodeData(codec Codec, v interface{}, compressThreshold int) (string, error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if compressThreshold > 0 && len(data) >= compressThreshold {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		writer.Write(data)
+		if err := writer.Close(); err != nil {
+			return "", err
+		}
+		// 压缩之后的 base64 比原数据还大时不压缩
+		if base64.StdEncoding.EncodedLen(buf.Len()) < len(data) {
+			return string(encodingGzip) + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+		}
+	}
+	if utf8.Valid(data) {
+		return string(encodingPlain) + string(data), nil
+	}
+	return string(encodingBase64) + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// 解码 encodeData 的结果
+func decodeData(codec Codec, s string, v interface{}) error {
+	if s == "" {
+		return fmt.Errorf("数据为空")
+	}
+	var data []byte
+	switch s[0] {
+	case encodingPlain:
+		data = []byte(s[1:])
+	case encodingBase64, encodingGzip:
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(s[1:]); err != nil {
+			return err
+		}
+		if s[0] == encodingGzip {
+			reader, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			if data, err = ioutil.ReadAll(reader); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的数据格式: %c", s[0])
+	}
+	return codec.Unmarshal(data, v)
+}
@@ -0,0 +1,122 @@
+package distdCache
+
+import (
+	"crypto/md5"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"yelo/go-util/convertor"
+	"yelo/go-util/timeUtil"
+)
+
+type testUser struct {
+	Name string
+	Tags []string
+}
+
+func TestEncodeData(t *testing.T) {
+	user := &testUser{Name: "tom", Tags: []string{strings.Repeat("a", 200)}}
+	for _, codec := range []Codec{JsonCodec, GobCodec} {
+		for _, threshold := range []int{0, 100} {
+			s, err := encodeData(codec, user, threshold)
+			if err != nil {
+				t.Errorf("error occured: %v", err)
+				continue
+			}
+			if compressed := s[0] == encodingGzip; compressed != (threshold > 0) {
+				t.Errorf("assert faild, threshold=%v, encoded=%v", threshold, s)
+			}
+			v := &testUser{}
+			if err := decodeData(codec, s, v); err != nil || !reflect.DeepEqual(v, user) {
+				t.Errorf("assert faild, err=%v, v=%v", err, v)
+			}
+		}
+	}
+
+	s, err := encodeData(RawCodec, []byte{0xff, 0x00}, 0)
+	var data []byte
+	if err != nil || s[0] != encodingBase64 || decodeData(RawCodec, s, &data) != nil || !reflect.DeepEqual(data, []byte{0xff, 0x00}) {
+		t.Errorf("assert faild, err=%v, encoded=%v, data=%v", err, s, data)
+	}
+	if _, err := encodeData(RawCodec, 123, 0); err == nil {
+		t.Errorf("RawCodec should not support int")
+	}
+}
+
+func TestCache_Codec(t *testing.T) {
+	backend := NewMemoryBackend()
+	var caches []Cache
+	for i := 0; i < 2; i++ {
+		manager := NewCacheManagerWithBackend("", backend, &CacheManagerOptions{SyncCheckInterval: time.Minute})
+		cache, err := manager.NewCache("test-codec", func() interface{} { return &testUser{} }, &CacheOption{BucketCount: 4, Codec: GobCodec, CompressThreshold: 64})
+		if err != nil {
+			t.Fatalf("error occured: %v", err)
+		}
+		if err := cache.Start(); err != nil {
+			t.Fatalf("error occured: %v", err)
+		}
+		caches = append(caches, cache)
+	}
+
+	user := &testUser{Name: "tom", Tags: []string{strings.Repeat("a", 100)}}
+	if err := caches[0].Set("u1", user); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return reflect.DeepEqual(caches[1].GetData("u1"), user) }) {
+		t.Errorf("assert faild: %v", caches[1].GetData("u1"))
+	}
+
+	// 旧版本以 Json 格式保存的数据
+	legacy := NewMemoryBackend()
+	impl := caches[0].(*cacheImpl)
+	legacy.HSet(impl.getBucketDataKey(impl.getBucketIndexByKey("u2")), "u2", `{"data":{"Name":"jerry"},"time":1600000000000}`)
+	cache, _ := NewCacheManagerWithBackend("", legacy, nil).NewCache("test-codec", func() interface{} { return &testUser{} }, &CacheOption{BucketCount: 4})
+	if err := cache.Start(); err != nil {
+		t.Fatalf("error occured: %v", err)
+	}
+	if v, ok := cache.GetData("u2").(*testUser); !ok || v.Name != "jerry" {
+		t.Errorf("assert faild: %v", cache.GetData("u2"))
+	}
+}
+
+func TestCache_LegacyFormat(t *testing.T) {
+	backend := NewMemoryBackend()
+	cache := newTestCluster(t, backend, "test-legacy", 1, &CacheOption{BucketCount: 4})[0]
+	impl := cache.(*cacheImpl)
+
+	// 旧版本的节点发送的变更消息：Val 为 Json 格式的数据，没有格式标记
+	msg := fmt.Sprintf(`{"c":"old-node","n":"test-legacy","opr":"set","k":"u1","v":"pizza","t":%v}`, timeUtil.ToMs(time.Now()))
+	if err := backend.Publish(msgQueueChannel, msg); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return cache.GetData("u1") == "pizza" }) {
+		t.Errorf("assert faild: %v", cache.GetData("u1"))
+	}
+
+	// 存储的数据以及 ETag 与旧版本的格式相同
+	if err := cache.Set("u2", "tom"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	index := impl.getBucketIndexByKey("u2")
+	val := cache.Get("u2")
+	legacy := convertor.ToStringNoError(&CacheEntity{Data: "tom", Time: val.Time})
+	if s, _, _ := backend.HGet(impl.getBucketDataKey(index), "u2"); s != legacy {
+		t.Errorf("assert faild: %v", s)
+	}
+	bucket := impl.buckets[index]
+	bucket.lock.Lock()
+	arr := make([]string, 0, len(bucket.data))
+	for k, v := range bucket.data {
+		arr = append(arr, k+"="+convertor.ToStringNoError(v))
+	}
+	sort.Strings(arr)
+	bucket.etagTime = 0
+	impl.updateEtag(bucket, timeUtil.ToMs(time.Now().Add(time.Hour)))
+	if etag := fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(arr, "\n")))); bucket.etag != etag {
+		t.Errorf("assert faild: %v != %v", bucket.etag, etag)
+	}
+	bucket.lock.Unlock()
+}
@@ -18,8 +18,10 @@
 // 具体方案：
 // 模块在内存中维持 key-value 数据缓存副本，同时附加保存数据的最后修改时间。
 // 每次修改数据前，先向 redis 写入数据，确保 redis 中的数据是最准确的并作为同步基准。同时通过消息队列通知其他节点数据已经发生变更。
-// 节点监听消息队列，当收到数据变更通知时，根据消息内容修改本地缓存数据。 考虑到消息存在延迟，所以在收到消息时先对比消息和本地的数据更新时间，判断哪边的数据更新。
-//   注意!!! 这里假定且依赖系统中的各节点时间是同步的
+// 节点监听消息队列，当收到数据变更通知时，根据消息内容修改本地缓存数据。 考虑到消息存在延迟，所以在收到消息时先对比消息和本地的数据版本号，判断哪边的数据更新。
+//   数据的版本号取本地时间与节点已知的最大版本号 +1 中的较大值（单位与毫秒时间戳相同），节点收到消息或者同步数据时会推进已知的最大版本号。
+//   写入 redis 时按版本号条件写入（由 Lua 脚本保证原子性），redis 中已经有相同或者更大的版本号时，节点将时钟推进到该版本号之后重新生成版本号再次写入。
+//   因此同一个 key 的修改按写入 redis 的先后决定新旧，后写入的修改总是生效，不需要各节点的时间保持同步。ETag 同步按各节点本地收到数据的时间计算，也不受时间偏差的影响。
 //   由于需要对比修改时间，所以对于del操作，不能直接删除，而是先通过一个字段deleted标记该key是否被删除，延迟一段时间（大致为 2 个同步间隔）再删除
 // 各模块定期比较本地数据与 redis 中数据的一致性，如果发现本地与 redis 有差异则从服务端同步最新数据。
 //
@@ -52,11 +54,11 @@
 package distdCache
 
 import (
+	"errors"
 	"github.com/go-redis/redis"
 	"strings"
 	"time"
 	"yelo/go-util/log"
-	"yelo/go-util/redisLock"
 	"yelo/go-util/strUtil"
 	"yelo/go-util/timeUtil"
 )
@@ -88,14 +90,26 @@ type Cache interface {
 	AllKeys() []string
 	// 获取一个值，key 区分大小写
 	Get(key string) CacheEntity
+	// 获取一个值，key 区分大小写。本地不存在时调用 loader（为 nil 时使用 CacheOption.Loader）加载数据，并通过 Set 同步到所有节点。
+	// 同一个节点中并发加载同一个 key 时只会调用一次 loader，不同节点之间通过分布式锁确保同一时间只有一个节点加载同一个 key。
+	// loader 返回 nil 时不缓存，返回空的 CacheEntity。
+	GetOrLoad(key string, loader LoaderFunc) (CacheEntity, error)
+	// 批量获取多个值，返回的 map 中只包含存在的 key
+	GetMulti(keys []string) map[string]CacheEntity
 	// 获取所有的值
 	GetAll() map[string]CacheEntity
 	// 获取一个值，key 区分大小写
 	GetData(key string) interface{}
-	// 设置一个值，key 区分大小写
+	// 设置一个值，key 区分大小写。
+	// 存储后端中已经有相同或者更大的版本号时使用更大的版本号重试，多个节点持续修改同一个 key 导致多次重试仍然冲突时返回 ErrVersionConflict
 	Set(key string, val interface{}) error
-	// 删除一个值，key 区分大小写
+	// 删除一个值，key 区分大小写。与 Set 相同，多次重试仍然存在版本号冲突时返回 ErrVersionConflict
 	Del(key string) error
+	// 批量设置多个值（值为 nil 表示删除）。所有修改在一个事务中写入存储后端，并通过一条消息通知其他节点，其他节点按 bucket 整体更新。
+	// 与 Set 相同，版本号冲突的 key 会重试，多次重试之后仍然冲突的 key 被忽略，其他 key 正常修改，并返回包装了 ErrVersionConflict 的错误（可以通过 errors.Is 判断）
+	SetMulti(values map[string]interface{}) error
+	// 批量删除多个值，返回值与 SetMulti 相同
+	DelMulti(keys []string) error
 	// 清空数据
 	Clear() error
 	// 强制从服务端同步数据。
@@ -113,12 +127,19 @@ type CacheManagerOptions struct {
 }
 
 type CacheOption struct {
-	BucketCount int                  // 桶的数量，默认 100
-	Expire      time.Duration        // 过期时间
-	OnChange    OnChangeFunc         // 当数据发生改变时要执行的回调函数
-	KeyCodeFunc func(key string) int // 获取 Key 对应的 hashCode 的函数。数据将会放在 hashCode % BucketCount 对应的桶中。默认为 Crc32IEEE。
+	BucketCount       int                  // 桶的数量，默认 100
+	Expire            time.Duration        // 过期时间
+	OnChange          OnChangeFunc         // 当数据发生改变时要执行的回调函数
+	KeyCodeFunc       func(key string) int // 获取 Key 对应的 hashCode 的函数。数据将会放在 hashCode % BucketCount 对应的桶中。默认为 Crc32IEEE。
+	Codec             Codec                // 数据的编解码器，用于存储、变更消息以及计算 ETag，默认为 JsonCodec。使用 JsonCodec 并且不压缩时与旧版本的数据格式兼容，可以与旧版本的节点同时运行
+	CompressThreshold int                  // 编码之后的数据达到该大小（字节）时使用 gzip 压缩，0 表示不压缩
+	Loader            LoaderFunc           // GetOrLoad 默认使用的加载函数
+	LoadTimeout       time.Duration        // 加载数据的超时时间，用作分布式锁的有效期以及等待其他节点加载的最长时间，默认 10 秒
 }
 
+// 加载缓存数据的函数，返回的数据会通过 Set 写入缓存
+type LoaderFunc func(key string) (interface{}, error)
+
 // 当缓存数据发生改变时的事件回调函数
 //   opr: set|del
 //   key: 发生改变的 key
@@ -128,7 +149,10 @@ type OnChangeFunc func(opr, key string, val CacheEntity, source string)
 
 type CacheEntity struct {
 	Data interface{} `json:"data,omitempty" description:"缓存的值，如果构造缓存实例时指定了 newEntityFunc ，则为该函数返回的实例；否则为字符串"`
-	Time int64       `json:"time,omitempty" description:"最后一次修改的时间（毫秒）"`
+	Time int64       `json:"time,omitempty" description:"数据的版本号，即最后一次修改的时间（毫秒，取本地时间与已知的最大版本号 +1 中的较大值，可能略大于实际时间）"`
+
+	encoded   string // 使用 Codec 编码之后的 Data，各节点之间通过比较编码结果判断数据是否相同
+	localTime int64  // 本地数据更新为当前版本的时间（毫秒，本地时间），用于计算 ETag 的时间点和清除已删除的数据，不受其他节点时间偏差的影响
 }
 
 func (this CacheEntity) Valid() bool {
@@ -140,13 +164,24 @@ var (
 	emptyEntity = CacheEntity{}
 
 	DefaultCacheManagerOptions = CacheManagerOptions{}
+
+	// 多个节点持续修改同一个 key，多次重试之后仍然存在版本号冲突，本次修改没有生效
+	ErrVersionConflict = errors.New("存储后端中的数据持续被其他节点修改，本次修改没有生效")
 )
 
-// 创建一个 CacheManager 实例
+// 创建一个使用 redis 作为存储后端的 CacheManager 实例
 //   clientId: 客户端唯一ID。在分布式系统中，请确保不同实例的 ClientId 不同，否则具有相同 ClientId 的实例会出现数据不完整的情况。
-//   redisClient: redis 连接池
+//   redisOpt: redis 连接参数
 //   opt: 其他参数
 func NewCacheManager(clientId string, redisOpt *redis.Options, opt *CacheManagerOptions) CacheManager {
+	return NewCacheManagerWithBackend(clientId, NewRedisBackend(redis.NewClient(redisOpt)), opt)
+}
+
+// 创建一个使用指定存储后端的 CacheManager 实例
+//   clientId: 客户端唯一ID。在分布式系统中，请确保不同实例的 ClientId 不同，否则具有相同 ClientId 的实例会出现数据不完整的情况。
+//   backend: 存储后端，如 NewRedisBackend、NewMemoryBackend。多个 CacheManager 使用同一个 MemoryBackend 时可以模拟集群
+//   opt: 其他参数
+func NewCacheManagerWithBackend(clientId string, backend Backend, opt *CacheManagerOptions) CacheManager {
 	// ensure clientId
 	if clientId = strings.TrimSpace(clientId); clientId == "" {
 		clientId = strUtil.Rand(4)
@@ -172,12 +207,11 @@ func NewCacheManager(clientId string, redisOpt *redis.Options, opt *CacheManager
 		realOpt.Logger = log.EmptyLogger()
 	}
 
-	redisClient := redis.NewClient(redisOpt)
 	return &cacheManagerImpl{
 		clientId:      clientId,
-		redisClient:   redisClient,
+		backend:       backend,
+		clock:         &versionClock{},
 		cacheInstance: make(map[string]*cacheImpl, 16),
-		redisLock:     redisLock.New(redisClient, "DistdCache"),
 		opt:           realOpt,
 	}
 }
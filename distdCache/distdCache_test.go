@@ -0,0 +1,162 @@
+package distdCache
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"yelo/go-util/timeUtil"
+)
+
+var testNodeId int32
+
+// 使用同一个 MemoryBackend 创建多个 CacheManager，模拟由多个节点组成的集群
+func newTestCluster(t *testing.T, backend Backend, name string, n int, opt *CacheOption) []Cache {
+	caches := make([]Cache, n)
+	for i := range caches {
+		manager := NewCacheManagerWithBackend(fmt.Sprintf("node%d", atomic.AddInt32(&testNodeId, 1)), backend, &CacheManagerOptions{SyncCheckInterval: 200 * time.Millisecond})
+		var realOpt *CacheOption
+		if opt != nil {
+			realOpt = &CacheOption{}
+			*realOpt = *opt
+		}
+		cache, err := manager.NewCache(name, nil, realOpt)
+		if err != nil {
+			t.Fatalf("error occured: %v", err)
+		}
+		if err := cache.Start(); err != nil {
+			t.Fatalf("error occured: %v", err)
+		}
+		caches[i] = cache
+	}
+	return caches
+}
+
+func waitFor(timeout time.Duration, f func() bool) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if f() {
+			return true
+		}
+	}
+	return f()
+}
+
+func TestMemoryBackend_Cluster(t *testing.T) {
+	var lock sync.Mutex
+	changes := make(map[string]string)
+	onChange := func(opr, key string, val CacheEntity, source string) {
+		lock.Lock()
+		defer lock.Unlock()
+		changes[opr+" "+key] = source
+	}
+	backend := NewMemoryBackend()
+	caches := newTestCluster(t, backend, "test-cluster", 3, &CacheOption{BucketCount: 4, OnChange: onChange})
+
+	if err := caches[0].Set("a", "1"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return caches[1].GetData("a") == "1" && caches[2].GetData("a") == "1" }) {
+		t.Errorf("assert faild: %v, %v", caches[1].GetData("a"), caches[2].GetData("a"))
+	}
+
+	if err := caches[2].Del("a"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return caches[0].GetData("a") == nil && caches[1].GetData("a") == nil }) {
+		t.Errorf("assert faild: %v, %v", caches[0].GetData("a"), caches[1].GetData("a"))
+	}
+	lock.Lock()
+	if changes["set a"] == "" || changes["del a"] == "" {
+		t.Errorf("OnChange should be fired: %v", changes)
+	}
+	lock.Unlock()
+
+	// 新加入的节点从存储后端同步数据
+	caches[0].Set("b", "2")
+	if late := newTestCluster(t, backend, "test-cluster", 1, &CacheOption{BucketCount: 4})[0]; late.GetData("b") != "2" {
+		t.Errorf("assert faild: %v", late.GetData("b"))
+	}
+}
+
+func TestMemoryBackend_MessageLoss(t *testing.T) {
+	testMessageLoss(t, "test-loss", 0)
+}
+
+// 某个节点的时间快了 1 小时，其他节点的版本号随之推进，ETag 检查仍然能修复丢失的消息
+func TestMemoryBackend_MessageLossWithClockSkew(t *testing.T) {
+	testMessageLoss(t, "test-loss-skew", time.Hour)
+}
+
+func testMessageLoss(t *testing.T, name string, skew time.Duration) {
+	backend := NewMemoryBackend()
+	caches := newTestCluster(t, backend, name, 2, &CacheOption{BucketCount: 4})
+	caches[0].Manager().(*cacheManagerImpl).clock.Update(timeUtil.ToMs(time.Now().Add(skew)))
+
+	// 丢失所有消息，节点之间只能通过 ETag 检查修复数据。第二轮修改时两个节点的版本号都已经被推进
+	backend.SetMessageLoss(1)
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 10; i++ {
+			caches[0].Set(fmt.Sprintf("k%d", i), fmt.Sprintf("v%d-%d", i, round))
+		}
+		caches[1].Set("x", fmt.Sprintf("y%d", round))
+		time.Sleep(50 * time.Millisecond)
+		if caches[1].GetData("k0") == "v0-"+strconv.Itoa(round) || caches[0].GetData("x") == "y"+strconv.Itoa(round) {
+			t.Errorf("message should be lost")
+		}
+
+		if !waitFor(3*time.Second, func() bool {
+			for i := 0; i < 10; i++ {
+				if caches[1].GetData(fmt.Sprintf("k%d", i)) != fmt.Sprintf("v%d-%d", i, round) {
+					return false
+				}
+			}
+			return caches[0].GetData("x") == "y"+strconv.Itoa(round)
+		}) {
+			t.Errorf("data should be repaired by etag sync in round %v: %v, %v", round, caches[0].GetAll(), caches[1].GetAll())
+		}
+	}
+}
+
+func TestMemoryBackend_Lock(t *testing.T) {
+	backend := NewMemoryBackend()
+	if ok, _ := backend.Lock("a", time.Minute, 0); !ok {
+		t.Errorf("lock should succeed")
+	}
+	if ok, _ := backend.Lock("a", time.Minute, 20*time.Millisecond); ok {
+		t.Errorf("lock should fail")
+	}
+	backend.Unlock("a")
+	if ok, _ := backend.Lock("a", 20*time.Millisecond, 0); !ok {
+		t.Errorf("lock should succeed after unlock")
+	}
+	if ok, _ := backend.Lock("a", time.Minute, 100*time.Millisecond); !ok {
+		t.Errorf("lock should succeed after expired")
+	}
+}
+
+func TestMemoryBackend_VersionTombstone(t *testing.T) {
+	backend := NewMemoryBackend()
+	exec := func(version int64, set map[string]string, del ...string) {
+		batch := &HashBatch{Key: "data", Set: set, Del: del, VersionKey: "version", Version: version, Tombstone: time.Second}
+		if err := backend.ExecBatch([]*HashBatch{batch}); err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+	}
+	exec(1000, map[string]string{"a": "1", "b": "1"})
+	exec(2000, nil, "a", "b")
+	exec(2500, map[string]string{"b": "2"})
+	if versions, _ := backend.HGetAll("version"); len(versions) != 2 {
+		t.Errorf("assert faild: %v", versions)
+	}
+
+	// 超过保留时长之后，已删除的 key 的版本号被清除，没有被删除的 key 的版本号保留
+	exec(3001, map[string]string{"c": "1"})
+	if versions, _ := backend.HGetAll("version"); len(versions) != 2 || versions["a"] != "" || versions["b"] != "2500" {
+		t.Errorf("assert faild: %v", versions)
+	}
+	if deleted, _ := backend.HGetAll(getDeletedVersionKey("version")); len(deleted) != 0 {
+		t.Errorf("assert faild: %v", deleted)
+	}
+}
@@ -0,0 +1,109 @@
+package distdCache
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 节点内正在进行的一次加载，并发加载同一个 key 的调用者共享加载结果
+type loadCall struct {
+	wg  sync.WaitGroup
+	val CacheEntity
+	err error
+}
+
+func (this *cacheImpl) GetOrLoad(key string, loader LoaderFunc) (CacheEntity, error) {
+	if val := this.Get(key); val.Valid() {
+		return val, nil
+	}
+	if loader == nil {
+		loader = this.opt.Loader
+	}
+	if loader == nil {
+		return emptyEntity, fmt.Errorf("没有设置 Loader")
+	}
+	if !this.started {
+		return emptyEntity, fmt.Errorf("请先调用 Start 方法启动缓存")
+	}
+
+	this.loadingLock.Lock()
+	if call := this.loading[key]; call != nil {
+		this.loadingLock.Unlock()
+		call.wg.Wait()
+		return call.val, call.err
+	}
+	call := &loadCall{err: fmt.Errorf("加载数据失败: %v", key)}
+	call.wg.Add(1)
+	this.loading[key] = call
+	this.loadingLock.Unlock()
+
+	defer func() {
+		// loader 发生 panic 时，等待的调用者得到默认的错误
+		this.loadingLock.Lock()
+		delete(this.loading, key)
+		this.loadingLock.Unlock()
+		call.wg.Done()
+	}()
+	call.val, call.err = this.doLoad(key, loader)
+	return call.val, call.err
+}
+
+func (this *cacheImpl) doLoad(key string, loader LoaderFunc) (CacheEntity, error) {
+	// 同一时间只有一个节点加载同一个 key。等待超时（持有锁的节点加载过慢或者已经退出）时不再等待，直接加载
+	lockName := this.getLoadLockName(key)
+	locked, err := this.manager.backend.Lock(lockName, this.opt.LoadTimeout, this.opt.LoadTimeout)
+	if err != nil {
+		return emptyEntity, fmt.Errorf("加锁失败: %v", err)
+	} else if locked {
+		defer this.manager.backend.Unlock(lockName)
+	} else {
+		this.manager.opt.Logger.Debug("[%v-%v] load lock timeout: %v", this.name, this.manager.clientId, lockName)
+	}
+
+	// 等待锁的过程中，其他节点可能已经加载完成
+	if val, err := this.loadFromBackend(key); err != nil || val.Valid() {
+		return val, err
+	}
+
+	data, err := loader(key)
+	if err != nil {
+		return emptyEntity, err
+	} else if data == nil {
+		return emptyEntity, nil
+	}
+	if err := this.Set(key, data); err != nil {
+		return emptyEntity, err
+	}
+	return this.Get(key), nil
+}
+
+// 从本地或者存储后端读取数据。存储后端中的数据比本地新时（变更消息尚未到达）更新本地数据
+func (this *cacheImpl) loadFromBackend(key string) (CacheEntity, error) {
+	if val := this.Get(key); val.Valid() {
+		return val, nil
+	}
+	s, ok, err := this.manager.backend.HGet(this.getBucketDataKey(this.getBucketIndexByKey(key)), key)
+	if err != nil {
+		return emptyEntity, fmt.Errorf("读取 redis 数据失败: %v", err)
+	} else if !ok {
+		return emptyEntity, nil
+	}
+	val, err := this.unmarshalStored(s)
+	if err != nil {
+		return emptyEntity, err
+	}
+	this.manager.clock.Update(val.Time)
+	if val.Data == nil {
+		if val.Data, err = this.decode(val.encoded); err != nil {
+			return emptyEntity, fmt.Errorf("数据解码失败: %v", err)
+		}
+	}
+	if err := this.doEdit(Operator_Set, key, val, "sync"); err != nil {
+		return emptyEntity, err
+	}
+	return this.Get(key), nil
+}
+
+func (this *cacheImpl) getLoadLockName(key string) string {
+	return fmt.Sprintf("%s.Load-%s", this.syncLockName, key)
+}
@@ -0,0 +1,65 @@
+package distdCache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_GetOrLoad(t *testing.T) {
+	var loads int32
+	loader := func(key string) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(100 * time.Millisecond)
+		if key == "missing" {
+			return nil, nil
+		} else if key == "error" {
+			return nil, fmt.Errorf("load error")
+		}
+		return "value of " + key, nil
+	}
+	caches := newTestCluster(t, NewMemoryBackend(), "test-load", 3, &CacheOption{BucketCount: 4, Loader: loader})
+
+	// 所有节点并发加载同一个 key，只加载一次
+	var wg sync.WaitGroup
+	for _, cache := range caches {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(cache Cache) {
+				defer wg.Done()
+				if val, err := cache.GetOrLoad("k1", nil); err != nil || val.Data != "value of k1" {
+					t.Errorf("assert faild, err=%v, val=%v", err, val)
+				}
+			}(cache)
+		}
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("should load once, got %v", n)
+	}
+	for _, cache := range caches {
+		if cache.GetData("k1") != "value of k1" {
+			t.Errorf("assert faild: %v", cache.GetData("k1"))
+		}
+	}
+
+	// 已经缓存的数据不再加载
+	if val, err := caches[1].GetOrLoad("k1", nil); err != nil || val.Data != "value of k1" || atomic.LoadInt32(&loads) != 1 {
+		t.Errorf("assert faild, err=%v, val=%v, loads=%v", err, val, loads)
+	}
+
+	// loader 返回 nil 或者错误时不缓存
+	if val, err := caches[0].GetOrLoad("missing", nil); err != nil || val.Valid() {
+		t.Errorf("assert faild, err=%v, val=%v", err, val)
+	}
+	if _, err := caches[0].GetOrLoad("error", nil); err == nil || caches[0].GetData("error") != nil {
+		t.Errorf("load error should be returned")
+	}
+
+	// 指定 loader
+	if val, err := caches[2].GetOrLoad("k2", func(key string) (interface{}, error) { return 123, nil }); err != nil || val.Data != "123" {
+		t.Errorf("assert faild, err=%v, val=%v", err, val)
+	}
+}
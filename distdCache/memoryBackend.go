@@ -0,0 +1,278 @@
+package distdCache
+
+import (
+	"math/rand"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 进程内的存储后端。
+// 同一个 MemoryBackend 可以同时被多个 CacheManager 使用，用于在单元测试中模拟由多个节点组成的集群；也可以用于不需要 redis 的单节点部署。
+// 通过 SetMessageLoss 可以模拟消息丢失，用于验证 ETag 同步机制能够修复不一致的数据。
+type MemoryBackend struct {
+	data        map[string]map[string]string // key => hash
+	expires     map[string]time.Time         // key => 过期时间
+	locks       map[string]time.Time         // 锁 => 过期时间
+	subscribers map[string][]*memorySubscriber
+	lossRate    float64
+	rand        *rand.Rand
+	lock        sync.Mutex
+}
+
+type memorySubscriber struct {
+	onMessage func(message string)
+	queue     chan string
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		data:        make(map[string]map[string]string),
+		expires:     make(map[string]time.Time),
+		locks:       make(map[string]time.Time),
+		subscribers: make(map[string][]*memorySubscriber),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// 设置消息丢失的概率（0~1），每个订阅者独立计算是否丢失。1 表示丢弃所有消息，0 表示不丢失
+func (this *MemoryBackend) SetMessageLoss(rate float64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.lossRate = rate
+}
+
+func (this *MemoryBackend) Ping() error {
+	return nil
+}
+
+func (this *MemoryBackend) Type(key string) (string, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.hash(key) == nil {
+		return "none", nil
+	}
+	return "hash", nil
+}
+
+func (this *MemoryBackend) Keys(pattern string) ([]string, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	keys := make([]string, 0)
+	for key := range this.data {
+		if this.hash(key) == nil {
+			continue
+		}
+		if ok, err := path.Match(pattern, key); err != nil {
+			return nil, err
+		} else if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (this *MemoryBackend) Del(keys ...string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, key := range keys {
+		delete(this.data, key)
+		delete(this.expires, key)
+	}
+	return nil
+}
+
+func (this *MemoryBackend) Expire(key string, ttl time.Duration) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.hash(key) != nil {
+		this.expires[key] = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (this *MemoryBackend) HGetAll(key string) (map[string]string, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	hash := this.hash(key)
+	dict := make(map[string]string, len(hash))
+	for k, v := range hash {
+		dict[k] = v
+	}
+	return dict, nil
+}
+
+func (this *MemoryBackend) HGet(key, field string) (string, bool, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	val, ok := this.hash(key)[field]
+	return val, ok, nil
+}
+
+func (this *MemoryBackend) HSet(key, field, value string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	hash := this.hash(key)
+	if hash == nil {
+		hash = make(map[string]string)
+		this.data[key] = hash
+	}
+	hash[field] = value
+	return nil
+}
+
+func (this *MemoryBackend) HDel(key string, fields ...string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if hash := this.hash(key); hash != nil {
+		for _, field := range fields {
+			delete(hash, field)
+		}
+		if len(hash) == 0 {
+			delete(this.data, key)
+			delete(this.expires, key)
+		}
+	}
+	return nil
+}
+
+func (this *MemoryBackend) ExecBatch(batches []*HashBatch) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, batch := range batches {
+		hash := this.hash(batch.Key)
+		if hash == nil {
+			hash = make(map[string]string)
+			this.data[batch.Key] = hash
+		}
+		var versions, deleted map[string]string
+		deletedKey := ""
+		if batch.VersionKey != "" {
+			if versions = this.hash(batch.VersionKey); versions == nil {
+				versions = make(map[string]string)
+				this.data[batch.VersionKey] = versions
+			}
+			deletedKey = getDeletedVersionKey(batch.VersionKey)
+			if deleted = this.hash(deletedKey); deleted == nil {
+				deleted = make(map[string]string)
+				this.data[deletedKey] = deleted
+			}
+		}
+		// 检查版本号，并记录新的版本号
+		newer := func(field string) bool {
+			if versions == nil {
+				return true
+			}
+			if v, err := strconv.ParseInt(versions[field], 10, 64); err == nil && v >= batch.Version {
+				if batch.Conflicts == nil {
+					batch.Conflicts = make(map[string]int64)
+				}
+				batch.Conflicts[field] = v
+				return false
+			}
+			versions[field] = strconv.FormatInt(batch.Version, 10)
+			return true
+		}
+		for k, v := range batch.Set {
+			if newer(k) {
+				hash[k] = v
+				delete(deleted, k)
+			}
+		}
+		for _, k := range batch.Del {
+			if newer(k) {
+				delete(hash, k)
+				if deleted != nil {
+					deleted[k] = versions[k]
+				}
+			}
+		}
+		// 清除过期的已删除字段的版本号
+		if batch.Tombstone > 0 {
+			before := batch.Version - int64(batch.Tombstone/time.Millisecond)
+			for k, v := range deleted {
+				if n, _ := strconv.ParseInt(v, 10, 64); n < before {
+					delete(versions, k)
+					delete(deleted, k)
+				}
+			}
+		}
+		for _, key := range []string{batch.Key, batch.VersionKey, deletedKey} {
+			if key == "" {
+				continue
+			} else if len(this.data[key]) == 0 {
+				delete(this.data, key)
+				delete(this.expires, key)
+			} else if batch.Expire > 0 {
+				this.expires[key] = time.Now().Add(batch.Expire)
+			}
+		}
+	}
+	return nil
+}
+
+func (this *MemoryBackend) Publish(channel, message string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, subscriber := range this.subscribers[channel] {
+		if this.lossRate > 0 && this.rand.Float64() < this.lossRate {
+			continue
+		}
+		select {
+		case subscriber.queue <- message:
+		default:
+			// 与 redis 一样，订阅者消费过慢时丢弃消息
+		}
+	}
+	return nil
+}
+
+func (this *MemoryBackend) Subscribe(channel string, onMessage func(message string), onError func(err error)) error {
+	subscriber := &memorySubscriber{onMessage: onMessage, queue: make(chan string, 10240)}
+	this.lock.Lock()
+	this.subscribers[channel] = append(this.subscribers[channel], subscriber)
+	this.lock.Unlock()
+	go func() {
+		for message := range subscriber.queue {
+			subscriber.onMessage(message)
+		}
+	}()
+	return nil
+}
+
+func (this *MemoryBackend) Lock(key string, ttl, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		this.lock.Lock()
+		now := time.Now()
+		if expires, ok := this.locks[key]; !ok || now.After(expires) {
+			this.locks[key] = now.Add(ttl)
+			this.lock.Unlock()
+			return true, nil
+		}
+		this.lock.Unlock()
+		if timeout <= 0 || now.After(deadline) {
+			return false, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (this *MemoryBackend) Unlock(key string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.locks, key)
+	return nil
+}
+
+// 获取未过期的 hash，调用者需要加锁
+func (this *MemoryBackend) hash(key string) map[string]string {
+	if expires, ok := this.expires[key]; ok && time.Now().After(expires) {
+		delete(this.data, key)
+		delete(this.expires, key)
+	}
+	return this.data[key]
+}
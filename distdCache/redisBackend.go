@@ -0,0 +1,214 @@
+package distdCache
+
+import (
+	"github.com/go-redis/redis"
+	"time"
+	"yelo/go-util/redisLock"
+)
+
+type redisBackend struct {
+	client *redis.Client
+	lock   redisLock.RedisLock
+}
+
+// 创建基于 redis 的存储后端，分布式锁使用 redisLock 实现
+func NewRedisBackend(client *redis.Client) Backend {
+	return &redisBackend{client: client, lock: redisLock.New(client, "DistdCache")}
+}
+
+func (this *redisBackend) Ping() error {
+	return this.client.Ping().Err()
+}
+
+func (this *redisBackend) Type(key string) (string, error) {
+	return this.client.Type(key).Result()
+}
+
+func (this *redisBackend) Keys(pattern string) ([]string, error) {
+	keys, err := this.client.Keys(pattern).Result()
+	if err == redis.Nil {
+		err = nil
+	}
+	return keys, err
+}
+
+func (this *redisBackend) Del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return ignoreNil(this.client.Del(keys...).Err())
+}
+
+func (this *redisBackend) Expire(key string, ttl time.Duration) error {
+	return ignoreNil(this.client.Expire(key, ttl).Err())
+}
+
+func (this *redisBackend) HGetAll(key string) (map[string]string, error) {
+	dict, err := this.client.HGetAll(key).Result()
+	if err == redis.Nil {
+		err = nil
+	}
+	return dict, err
+}
+
+func (this *redisBackend) HGet(key, field string) (string, bool, error) {
+	val, err := this.client.HGet(key, field).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+func (this *redisBackend) HSet(key, field, value string) error {
+	return ignoreNil(this.client.HSet(key, field, value).Err())
+}
+
+func (this *redisBackend) HDel(key string, fields ...string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return ignoreNil(this.client.HDel(key, fields...).Err())
+}
+
+func (this *redisBackend) ExecBatch(batches []*HashBatch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+	cmds := make([]*redis.Cmd, len(batches))
+	_, err := this.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		for i, batch := range batches {
+			if batch.VersionKey != "" {
+				var pruneBefore int64
+				if batch.Tombstone > 0 {
+					pruneBefore = batch.Version - int64(batch.Tombstone/time.Millisecond)
+				}
+				args := make([]interface{}, 0, 3+2*(len(batch.Set)+len(batch.Del)))
+				args = append(args, batch.Version, int64(batch.Expire/time.Millisecond), pruneBefore)
+				for k, v := range batch.Set {
+					args = append(args, k, v)
+				}
+				for _, k := range batch.Del {
+					args = append(args, k, "")
+				}
+				cmds[i] = pipe.Eval(versionedBatchScript, []string{batch.Key, batch.VersionKey, getDeletedVersionKey(batch.VersionKey)}, args...)
+				continue
+			}
+			if len(batch.Set) != 0 {
+				fields := make(map[string]interface{}, len(batch.Set))
+				for k, v := range batch.Set {
+					fields[k] = v
+				}
+				pipe.HMSet(batch.Key, fields)
+			}
+			if len(batch.Del) != 0 {
+				pipe.HDel(batch.Key, batch.Del...)
+			}
+			if batch.Expire > 0 {
+				pipe.Expire(batch.Key, batch.Expire)
+			}
+		}
+		return nil
+	})
+	if err = ignoreNil(err); err != nil {
+		return err
+	}
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		conflicts, _ := cmd.Val().([]interface{})
+		for j := 0; j+1 < len(conflicts); j += 2 {
+			field, ok := conflicts[j].(string)
+			version, ok2 := conflicts[j+1].(int64)
+			if ok && ok2 {
+				if batches[i].Conflicts == nil {
+					batches[i].Conflicts = make(map[string]int64)
+				}
+				batches[i].Conflicts[field] = version
+			}
+		}
+	}
+	return nil
+}
+
+// 带版本号的批量修改。
+// KEYS: 数据 hash、版本号 hash、已删除字段的 zset（score 为删除时的版本号）
+// ARGV: 版本号、过期时间（毫秒）、清除该版本号之前删除的字段的版本号（0 表示不清除）、字段1、值1、字段2、值2...（值为空表示删除）
+// 返回由于已有相同或者更新的版本而没有修改的字段及其当前版本号：字段1、版本号1、字段2、版本号2...
+const versionedBatchScript = `
+local version = tonumber(ARGV[1])
+local conflicts = {}
+for i = 4, #ARGV, 2 do
+	local current = tonumber(redis.call('HGET', KEYS[2], ARGV[i]))
+	if current and current >= version then
+		table.insert(conflicts, ARGV[i])
+		table.insert(conflicts, current)
+	else
+		redis.call('HSET', KEYS[2], ARGV[i], ARGV[1])
+		if ARGV[i + 1] == '' then
+			redis.call('HDEL', KEYS[1], ARGV[i])
+			redis.call('ZADD', KEYS[3], ARGV[1], ARGV[i])
+		else
+			redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+			redis.call('ZREM', KEYS[3], ARGV[i])
+		end
+	end
+end
+if tonumber(ARGV[3]) > 0 then
+	local expired = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', '(' .. ARGV[3])
+	for _, field in ipairs(expired) do
+		redis.call('HDEL', KEYS[2], field)
+	end
+	redis.call('ZREMRANGEBYSCORE', KEYS[3], '-inf', '(' .. ARGV[3])
+end
+if tonumber(ARGV[2]) > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call('PEXPIRE', key, ARGV[2])
+	end
+end
+return conflicts
+`
+
+func (this *redisBackend) Publish(channel, message string) error {
+	return ignoreNil(this.client.Publish(channel, message).Err())
+}
+
+func (this *redisBackend) Subscribe(channel string, onMessage func(message string), onError func(err error)) error {
+	// 订阅需要独占一个连接
+	client := redis.NewClient(this.client.Options())
+	subscriber := client.Subscribe(channel)
+	if _, err := subscriber.Receive(); err != nil {
+		subscriber.Close()
+		client.Close()
+		return err
+	}
+	go func() {
+		defer client.Close()
+		defer subscriber.Close()
+		for {
+			if msg, err := subscriber.ReceiveMessage(); err != nil {
+				onError(err)
+			} else {
+				onMessage(msg.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+func (this *redisBackend) Lock(key string, ttl, timeout time.Duration) (bool, error) {
+	return this.lock.Lock(key, ttl, timeout)
+}
+
+func (this *redisBackend) Unlock(key string) error {
+	return this.lock.Unlock(key)
+}
+
+func ignoreNil(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
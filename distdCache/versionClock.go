@@ -0,0 +1,49 @@
+package distdCache
+
+import (
+	"sync"
+	"time"
+	"yelo/go-util/timeUtil"
+)
+
+// 生成数据版本号的时钟（以毫秒时间戳为基准的逻辑时钟）。
+// 版本号的单位与毫秒时间戳相同：生成版本号时取本地时间与已知最大版本号 +1 中的较大值，收到其他节点的数据时推进已知的最大版本号。
+// 因此在观察到某个版本之后产生的修改，其版本号一定比该版本大；各节点时间一致时版本号即为修改时间。
+// 写入存储后端时如果已经有相同或者更大的版本号（没有观察到的其他节点的修改），推进时钟之后重新生成版本号，所以修改的先后与各节点的时间无关。
+// 时间较快的节点会使其他节点的版本号随之推进，版本号可能一直大于实际时间，因此版本号只用于比较新旧，不用于计算 ETag 的时间点。
+type versionClock struct {
+	last int64
+	lock sync.Mutex
+}
+
+// 生成一个新的版本号
+func (this *versionClock) Now() int64 {
+	t := timeUtil.ToMs(time.Now())
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if t <= this.last {
+		t = this.last + 1
+	}
+	this.last = t
+	return t
+}
+
+// 获取已知的最大版本号（不生成新的版本号），不小于本地时间
+func (this *versionClock) Peek() int64 {
+	t := timeUtil.ToMs(time.Now())
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if t < this.last {
+		t = this.last
+	}
+	return t
+}
+
+// 收到其他节点的版本号时推进时钟
+func (this *versionClock) Update(t int64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if t > this.last {
+		this.last = t
+	}
+}
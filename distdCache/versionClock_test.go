@@ -0,0 +1,130 @@
+package distdCache
+
+import (
+	"testing"
+	"time"
+	"yelo/go-util/timeUtil"
+)
+
+func TestVersionClock(t *testing.T) {
+	clock := &versionClock{}
+	if a, b := clock.Now(), clock.Now(); b <= a {
+		t.Errorf("version should increase: %v, %v", a, b)
+	}
+
+	// 收到更大的版本号之后，生成的版本号一定比它大
+	future := timeUtil.ToMs(time.Now().Add(time.Hour))
+	clock.Update(future)
+	if v := clock.Now(); v <= future {
+		t.Errorf("assert faild: %v <= %v", v, future)
+	}
+	if v := clock.Peek(); v != future+1 {
+		t.Errorf("assert faild: %v", v)
+	}
+}
+
+func TestCache_ClockSkew(t *testing.T) {
+	backend := NewMemoryBackend()
+	caches := newTestCluster(t, backend, "test-skew", 2, &CacheOption{BucketCount: 4})
+
+	// node0 的时间比 node1 快 1 小时
+	caches[0].Manager().(*cacheManagerImpl).clock.Update(timeUtil.ToMs(time.Now().Add(time.Hour)))
+	if err := caches[0].Set("k", "a"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return caches[1].GetData("k") == "a" }) {
+		t.Errorf("assert faild: %v", caches[1].GetData("k"))
+	}
+
+	// node1 在观察到 node0 的修改之后再修改，即使 node1 的时间较慢，它的修改也会生效
+	if err := caches[1].Set("k", "b"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return caches[0].GetData("k") == "b" && caches[1].GetData("k") == "b" }) {
+		t.Errorf("assert faild: %v, %v", caches[0].GetData("k"), caches[1].GetData("k"))
+	}
+	if late := newTestCluster(t, backend, "test-skew", 1, &CacheOption{BucketCount: 4})[0]; late.GetData("k") != "b" {
+		t.Errorf("assert faild: %v", late.GetData("k"))
+	}
+
+	// 旧版本的修改（例如时间较慢、尚未收到变更消息的节点）写入存储后端时会被忽略
+	cache := caches[1].(*cacheImpl)
+	index := cache.getBucketIndexByKey("k")
+	version := cache.Get("k").Time
+	batch := &HashBatch{Key: cache.getBucketDataKey(index), Set: map[string]string{"k": "stale"}, VersionKey: cache.getBucketVersionKey(index), Version: version - 1}
+	if err := backend.ExecBatch([]*HashBatch{batch}); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if v, ok := batch.Conflicts["k"]; !ok || v != version {
+		t.Errorf("assert faild: %v", batch.Conflicts)
+	}
+	if s, _, _ := backend.HGet(cache.getBucketDataKey(index), "k"); s == "stale" {
+		t.Errorf("stale write should be skipped")
+	}
+
+	// 删除之后仍然保留版本号，旧版本的写入不会使数据复活
+	if err := caches[0].Del("k"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	batch = &HashBatch{Key: cache.getBucketDataKey(index), Set: map[string]string{"k": "stale"}, VersionKey: cache.getBucketVersionKey(index), Version: version}
+	if err := backend.ExecBatch([]*HashBatch{batch}); err != nil || len(batch.Conflicts) != 1 {
+		t.Errorf("assert faild, err=%v, conflicts=%v", err, batch.Conflicts)
+	}
+}
+
+func TestCache_VersionConflict(t *testing.T) {
+	cache := newTestCluster(t, NewMemoryBackend(), "test-conflict", 1, &CacheOption{BucketCount: 4})[0]
+	impl := cache.(*cacheImpl)
+	clientId := impl.manager.clientId
+	if err := cache.Set("k", "a"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	version := cache.Get("k").Time
+
+	// 写入存储后端期间本地数据已经被更新版本的消息修改，本地数据不会被旧版本覆盖
+	bucket := impl.buckets[impl.getBucketIndexByKey("k")]
+	bucket.lock.Lock()
+	bucket.data["k"] = &CacheEntity{Data: "c", Time: version + 1000, encoded: "c"}
+	bucket.lock.Unlock()
+	if err := impl.doEdit(Operator_Set, "k", &CacheEntity{Data: "b", Time: version + 1}, clientId); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if v := cache.Get("k"); v.Data != "c" || v.Time != version+1000 {
+		t.Errorf("assert faild: %v", v)
+	}
+}
+
+// 时间较慢的节点没有收到其他节点的变更消息时修改同一个 key，会推进时钟重试，后写入的修改生效
+func TestCache_VersionConflictRetry(t *testing.T) {
+	backend := NewMemoryBackend()
+	caches := newTestCluster(t, backend, "test-conflict-retry", 2, &CacheOption{BucketCount: 4})
+	caches[0].Manager().(*cacheManagerImpl).clock.Update(timeUtil.ToMs(time.Now().Add(time.Hour)))
+
+	backend.SetMessageLoss(1)
+	if err := caches[0].SetMulti(map[string]interface{}{"k": "a", "k2": "a", "k3": "a"}); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	backend.SetMessageLoss(0)
+	if err := caches[1].Set("k", "b"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if err := caches[1].SetMulti(map[string]interface{}{"k2": "b", "x": "b"}); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if err := caches[1].Del("k3"); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if !waitFor(time.Second, func() bool {
+		for _, cache := range caches {
+			if cache.GetData("k") != "b" || cache.GetData("k2") != "b" || cache.GetData("x") != "b" || cache.GetData("k3") != nil {
+				return false
+			}
+		}
+		return true
+	}) {
+		t.Errorf("assert faild: %v, %v", caches[0].GetAll(), caches[1].GetAll())
+	}
+	if late := newTestCluster(t, backend, "test-conflict-retry", 1, &CacheOption{BucketCount: 4})[0]; late.GetData("k") != "b" || late.GetData("k2") != "b" || late.GetData("k3") != nil {
+		t.Errorf("assert faild: %v", late.GetAll())
+	}
+}
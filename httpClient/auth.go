@@ -0,0 +1,274 @@
+package httpClient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 认证器，在每次（包括重试）请求发送之前为请求添加认证信息。
+// 通过 Client.SetAuth 对单个 Client 生效，或者通过 ClientConfig.Auth 对 profile 生效。
+type Authenticator interface {
+	Authenticate(c *Client, req *http.Request) error
+}
+
+// 可以刷新凭证的认证器。请求返回 401 时调用 Refresh 刷新凭证，之后重新认证并再发送一次请求（只重发一次）。
+type RefreshableAuthenticator interface {
+	Authenticator
+	// 刷新凭证，req 为返回 401 的请求
+	Refresh(c *Client, req *http.Request) error
+}
+
+// 获取认证器对应的拦截器
+func AuthInterceptor(a Authenticator) Interceptor {
+	return func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		if err := a.Authenticate(c, req); err != nil {
+			return nil, fmt.Errorf("认证失败: %v", err)
+		}
+		resp, err := next(req)
+		refresher, ok := a.(RefreshableAuthenticator)
+		if err != nil || !ok || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			// Request Body 无法重复读取
+			return resp, err
+		}
+
+		if err := refresher.Refresh(c, req); err != nil {
+			return resp, nil
+		}
+		retry, err := cloneRequest(req)
+		if err != nil {
+			return resp, nil
+		}
+		if err := a.Authenticate(c, retry); err != nil {
+			return resp, nil
+		}
+		resp.Body.Close()
+		return next(retry)
+	}
+}
+
+// 设置当前 Client 使用的认证器。等同于 AddInterceptor(AuthInterceptor(a))，
+// 但是总是在缓存拦截器（参考 SetCache）之前执行，以便缓存的 key 包含认证信息。
+func (this *Client) SetAuth(a Authenticator) *Client {
+	if a != nil {
+		this.addAuthInterceptor(a)
+	}
+	return this
+}
+
+func (this *Client) addAuthInterceptor(a Authenticator) {
+	if this.cacheIndex == 0 {
+		this.interceptors = append(this.interceptors, AuthInterceptor(a))
+		return
+	}
+	index := this.cacheIndex - 1
+	this.interceptors = append(this.interceptors, nil)
+	copy(this.interceptors[index+1:], this.interceptors[index:])
+	this.interceptors[index] = AuthInterceptor(a)
+	this.cacheIndex++
+}
+
+// ------------------------------------------------------------------------------ Basic
+// HTTP Basic 认证
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (this *BasicAuth) Authenticate(c *Client, req *http.Request) error {
+	req.SetBasicAuth(this.Username, this.Password)
+	return nil
+}
+
+// ------------------------------------------------------------------------------ Bearer
+// Bearer Token 认证，支持在 Token 过期（服务端返回 401）时通过回调函数刷新 Token。可以被多个 Client 同时使用。
+type BearerAuth struct {
+	token   string
+	refresh func() (string, error)
+	lock    sync.Mutex
+}
+
+// 创建 Bearer Token 认证器。refresh 为刷新 Token 的回调函数，为 nil 表示不刷新；token 为空时在第一次请求之前调用 refresh 获取 Token。
+// 多个请求同时返回 401 时只会刷新一次。
+func NewBearerAuth(token string, refresh func() (string, error)) *BearerAuth {
+	return &BearerAuth{token: token, refresh: refresh}
+}
+
+// 获取当前的 Token
+func (this *BearerAuth) Token() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.token
+}
+
+// 设置 Token
+func (this *BearerAuth) SetToken(token string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.token = token
+}
+
+func (this *BearerAuth) Authenticate(c *Client, req *http.Request) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.token == "" && this.refresh != nil {
+		token, err := this.refresh()
+		if err != nil {
+			return err
+		}
+		this.token = token
+	}
+	if this.token != "" {
+		req.Header.Set("Authorization", "Bearer "+this.token)
+	}
+	return nil
+}
+
+func (this *BearerAuth) Refresh(c *Client, req *http.Request) error {
+	if this.refresh == nil {
+		return fmt.Errorf("没有设置刷新 Token 的函数")
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if used := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); used != this.token {
+		// 其他请求已经刷新过 Token
+		return nil
+	}
+	token, err := this.refresh()
+	if err != nil {
+		return err
+	}
+	this.token = token
+	return nil
+}
+
+// ------------------------------------------------------------------------------ HMAC
+const (
+	HMACKeyHeader       = "X-Auth-Key"       // HMAC 签名使用的密钥 ID
+	HMACTimestampHeader = "X-Auth-Timestamp" // HMAC 签名的时间戳（Unix 秒）
+	HMACSignatureHeader = "X-Auth-Signature" // HMAC 签名（十六进制）
+)
+
+// HMAC 请求签名。签名的内容为（以 \n 分隔）：
+//   Method（大写）
+//   Path（转义之后的路径）
+//   Query 参数（按照参数名、参数值排序之后编码）
+//   时间戳（Unix 秒）
+//   Request Body 的 SHA256（十六进制）
+// 密钥 ID、时间戳、签名分别保存在 X-Auth-Key、X-Auth-Timestamp、X-Auth-Signature 中。服务端使用 HMACVerifier 校验。
+type HMACSigner struct {
+	KeyId  string           // 密钥 ID，服务端根据 ID 获取对应的密钥
+	Secret []byte           // 密钥
+	Hash   func() hash.Hash // 签名使用的哈希算法，默认 SHA256（需要与服务端一致）
+}
+
+func (this *HMACSigner) Authenticate(c *Client, req *http.Request) error {
+	body, err := readBodyForSign(req)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HMACKeyHeader, this.KeyId)
+	req.Header.Set(HMACTimestampHeader, timestamp)
+	req.Header.Set(HMACSignatureHeader, signHMAC(this.Hash, this.Secret, req, timestamp, body))
+	return nil
+}
+
+// HMAC 签名校验，与 HMACSigner 相对应
+type HMACVerifier struct {
+	Secret  func(keyId string) ([]byte, error) // 根据密钥 ID 获取密钥
+	Hash    func() hash.Hash                   // 签名使用的哈希算法，默认 SHA256
+	MaxSkew time.Duration                      // 允许的客户端与服务端的最大时间差，默认 5 分钟
+}
+
+// 校验请求的签名，成功时返回密钥 ID。校验之后 req.Body 仍然可以正常读取。
+func (this *HMACVerifier) Verify(req *http.Request) (string, error) {
+	keyId, timestamp, signature := req.Header.Get(HMACKeyHeader), req.Header.Get(HMACTimestampHeader), req.Header.Get(HMACSignatureHeader)
+	if keyId == "" || timestamp == "" || signature == "" {
+		return "", fmt.Errorf("缺少签名")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("时间戳格式错误: %v", timestamp)
+	}
+	maxSkew := this.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = 5 * time.Minute
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxSkew || skew < -maxSkew {
+		return "", fmt.Errorf("签名已过期")
+	}
+	secret, err := this.Secret(keyId)
+	if err != nil {
+		return "", fmt.Errorf("获取密钥失败: %v", err)
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(signature), []byte(signHMAC(this.Hash, secret, req, timestamp, body))) {
+		return "", fmt.Errorf("签名错误")
+	}
+	return keyId, nil
+}
+
+func signHMAC(h func() hash.Hash, secret []byte, req *http.Request, timestamp string, body []byte) string {
+	if h == nil {
+		h = sha256.New
+	}
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(h, secret)
+	io.WriteString(mac, strings.ToUpper(req.Method)+"\n"+req.URL.EscapedPath()+"\n"+canonicalQuery(req.URL.Query())+"\n"+timestamp+"\n"+hex.EncodeToString(bodyHash[:]))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// 按照参数名、参数值排序之后编码
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	for _, key := range keys {
+		vals := append([]string(nil), query[key]...)
+		sort.Strings(vals)
+		for _, val := range vals {
+			if buf.Len() != 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(key) + "=" + url.QueryEscape(val))
+		}
+	}
+	return buf.String()
+}
+
+// 读取用于签名的 Request Body。优先通过 GetBody 读取，以免影响请求的发送
+func readBodyForSign(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return readRequestBody(req)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
@@ -0,0 +1,125 @@
+package httpClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_BasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		w.Write([]byte(user + ":" + pass))
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL).SetAuth(&BasicAuth{Username: "admin", Password: "secret"}).Get()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if client.ResponseText() != "admin:secret" {
+		t.Errorf("assert faild: %v", client.ResponseText())
+	}
+}
+
+func TestClient_BearerAuthRefresh(t *testing.T) {
+	var token atomic.Value
+	token.Store("t1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token.Load().(string) {
+			w.WriteHeader(401)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var refreshed int32
+	auth := NewBearerAuth("", func() (string, error) {
+		n := atomic.AddInt32(&refreshed, 1)
+		return fmt.Sprintf("t%d", n), nil
+	})
+	client := Open().SetUrl(server.URL).SetAuth(auth).SetBody("hello").Post()
+	defer client.Close()
+	if err := client.Error(); err != nil || client.ResponseText() != "hello" || auth.Token() != "t1" {
+		t.Errorf("assert faild, err=%v, text=%v, token=%v", err, client.ResponseText(), auth.Token())
+	}
+
+	// Token 过期之后，多个请求同时返回 401 时只刷新一次
+	token.Store("t2")
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := Open().SetUrl(server.URL).SetAuth(auth).SetBody("hello").Post()
+			defer c.Close()
+			if err := c.Error(); err != nil || c.ResponseText() != "hello" {
+				t.Errorf("assert faild, err=%v, text=%v", err, c.ResponseText())
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&refreshed); n != 2 {
+		t.Errorf("token should be refreshed once, got %v", n)
+	}
+
+	// 刷新之后仍然返回 401 时不再重试
+	token.Store("never")
+	client.Reborn().SetUrl(server.URL).SetAuth(auth).Get()
+	if client.StatusCode != 401 || atomic.LoadInt32(&refreshed) != 3 {
+		t.Errorf("assert faild, status=%v, refreshed=%v", client.StatusCode, refreshed)
+	}
+}
+
+func TestClient_HMACAuth(t *testing.T) {
+	verifier := &HMACVerifier{Secret: func(keyId string) ([]byte, error) {
+		if keyId != "app1" {
+			return nil, fmt.Errorf("unknown key: %v", keyId)
+		}
+		return []byte("secret"), nil
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyId, err := verifier.Verify(r)
+		if err != nil {
+			w.WriteHeader(403)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(keyId + " " + string(body)))
+	}))
+	defer server.Close()
+
+	if err := RegisterProfile("test-hmac", &ClientConfig{Auth: &HMACSigner{KeyId: "app1", Secret: []byte("secret")}}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	client := Open("test-hmac").SetUrl(server.URL+"/api/orders").SetQuery("b", []int{2, 1}).SetQuery("a", "x y").SetBody(`{"id":1}`).Post()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v, %v", err, client.ResponseText())
+	} else if client.ResponseText() != `app1 {"id":1}` {
+		t.Errorf("assert faild: %v", client.ResponseText())
+	}
+
+	// 密钥错误
+	client2 := Open().SetUrl(server.URL + "/api/orders").SetAuth(&HMACSigner{KeyId: "app1", Secret: []byte("wrong")}).Get()
+	defer client2.Close()
+	if client2.StatusCode != 403 {
+		t.Errorf("expect 403, got %v", client2.StatusCode)
+	}
+
+	// 请求被篡改
+	req, _ := http.NewRequest("GET", server.URL+"/api/orders?a=1", nil)
+	(&HMACSigner{KeyId: "app1", Secret: []byte("secret")}).Authenticate(nil, req)
+	req.URL.RawQuery = "a=2"
+	if _, err := verifier.Verify(req); err == nil {
+		t.Errorf("tampered request should not be verified")
+	}
+}
@@ -0,0 +1,95 @@
+package httpClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 批量请求中单个请求的结果
+type BatchResult struct {
+	Param      *RequestParam // 请求参数
+	StatusCode int           // 状态码，请求没有得到服务端的响应时为 -1
+	Header     http.Header   // Response Header
+	Data       []byte        // Response Body
+	Err        error         // 请求过程中发生的错误，参考 Client.Error
+	Attempts   int           // 请求次数（包含重试）
+	Took       time.Duration // 耗时（包含重试）
+}
+
+// 获取 Response Body 的文本内容
+func (this *BatchResult) Text() string {
+	return string(this.Data)
+}
+
+// 根据 Response 的 Content-Type 选择解码器（参考 GetDecoder），将 Response Body 反序列化到参数指定的对象中。请求失败时返回请求的错误。
+func (this *BatchResult) Decode(obj interface{}) error {
+	if this.Err != nil {
+		return this.Err
+	}
+	contentType := this.Header.Get("Content-Type")
+	if err := GetDecoder(contentType)(this.Data, obj); err != nil {
+		return newDecodeError(contentType, this.Data, err)
+	}
+	return nil
+}
+
+// 并发发起多个 HTTP 请求，等待所有请求结束之后按照参数的顺序返回每个请求的结果。等同于 BatchRequestCtx(context.Background(), params, concurrency)
+func BatchRequest(params []*RequestParam, concurrency int) []*BatchResult {
+	return BatchRequestCtx(context.Background(), params, concurrency)
+}
+
+// 使用参数指定的 context.Context 并发发起多个 HTTP 请求，等待所有请求结束之后按照参数的顺序返回每个请求的结果。
+// 每个请求使用一个（从连接池中打开的）Client，按照 Client 的默认配置重试。
+// concurrency 为同时进行的最大请求数，<=0 表示不限制。context 结束之后尚未开始的请求不再发送，其结果的 Err 为 context 结束的原因。
+func BatchRequestCtx(ctx context.Context, params []*RequestParam, concurrency int) []*BatchResult {
+	results := make([]*BatchResult, len(params))
+	if concurrency <= 0 || concurrency > len(params) {
+		concurrency = len(params)
+	}
+
+	var wg sync.WaitGroup
+	next := int32(-1)
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(params) {
+					return
+				}
+				results[i] = doBatchRequest(ctx, params[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func doBatchRequest(ctx context.Context, param *RequestParam) *BatchResult {
+	result := &BatchResult{Param: param, StatusCode: -1}
+	if param == nil {
+		result.Err = fmt.Errorf("参数 param 不能为空")
+		return result
+	}
+	if ctx.Err() != nil {
+		result.Err = context.Cause(ctx)
+		return result
+	}
+
+	begin := time.Now()
+	client := Open()
+	defer client.Close()
+	client.SetRequestParam(param).RequestCtx(ctx)
+	result.StatusCode = client.StatusCode
+	result.Header = client.responseHeader
+	result.Data = client.responseData
+	result.Err = client.err
+	result.Attempts = client.attempts
+	result.Took = time.Since(begin)
+	return result
+}
@@ -0,0 +1,97 @@
+package httpClient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchRequest(t *testing.T) {
+	var running, maxRunning int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Query().Get("id") == "3" {
+			w.WriteHeader(404)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":` + r.URL.Query().Get("id") + `,"method":"` + r.Method + `","token":"` + r.Header.Get("X-Token") + `"}`))
+	}))
+	defer server.Close()
+
+	var params []*RequestParam
+	for i := 0; i < 10; i++ {
+		params = append(params, &RequestParam{Method: "GET", Host: server.URL, Api: "/item?id=" + strconv.Itoa(i), Header: map[string]string{"X-Token": "abc"}})
+	}
+	params[5].Method = "POST"
+	results := BatchRequest(params, 3)
+	if len(results) != len(params) {
+		t.Errorf("assert faild, got %v results", len(results))
+		return
+	}
+	for i, v := range results {
+		var obj struct {
+			Id     int    `json:"id"`
+			Method string `json:"method"`
+			Token  string `json:"token"`
+		}
+		err := v.Decode(&obj)
+		if i == 3 {
+			if err == nil || v.StatusCode != 404 {
+				t.Errorf("expect 404 error, got status=%v, err=%v", v.StatusCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		} else if obj.Id != i || obj.Method != params[i].Method || obj.Token != "abc" || v.Param != params[i] {
+			t.Errorf("assert faild: %v, %+v", i, obj)
+		}
+	}
+	if n := atomic.LoadInt32(&maxRunning); n > 3 {
+		t.Errorf("concurrency should be limited to 3, got %v", n)
+	}
+}
+
+func TestBatchRequestCtx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	var params []*RequestParam
+	for i := 0; i < 4; i++ {
+		params = append(params, &RequestParam{Host: server.URL})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	for i, v := range BatchRequestCtx(ctx, params, 1) {
+		if v.Err == nil {
+			t.Errorf("request %v should be canceled", i)
+		}
+	}
+}
+
+func TestClient_SetRequestParam(t *testing.T) {
+	client := Open().SetMethod("post").SetUrl("http://example.com/api?a=1").SetHeader("X-Token", "abc").SetBody(`{"a":1}`)
+	client.AddCookie(&http.Cookie{Name: "sid", Value: "123"})
+	defer client.Close()
+	params := client.RequestParams()
+
+	client2 := Open().SetRequestParam(params)
+	defer client2.Close()
+	if v := client2.RequestParams(); v.Method != "POST" || v.Host != "http://example.com" || v.Api != "/api?a=1" || v.Header["X-Token"] != "abc" || v.Body != `{"a":1}` || len(v.Cookie) != 1 || v.Cookie[0] != "sid=123" {
+		t.Errorf("assert faild: %+v", v)
+	}
+}
@@ -0,0 +1,232 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = 0 // 关闭（正常放行请求）
+	CircuitOpen     CircuitState = 1 // 打开（熔断，请求直接失败）
+	CircuitHalfOpen CircuitState = 2 // 半开（放行少量探测请求，根据探测结果决定关闭还是重新打开）
+)
+
+func (this CircuitState) String() string {
+	switch this {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(this))
+}
+
+type CircuitBreakerOptions struct {
+	FailureRatio   float64                                   // 统计窗口内失败请求的比例达到该值时打开熔断器，默认 0.5
+	MinRequests    int                                       // 统计窗口内请求数达到该值时才会计算失败比例，默认 20
+	Window         time.Duration                             // 统计窗口，默认 10 秒
+	OpenDuration   time.Duration                             // 熔断器打开之后，持续多长时间进入半开状态，默认 5 秒
+	HalfOpenProbes int                                       // 半开状态下允许的探测请求数，全部成功时关闭熔断器，默认 1
+	IsFailure      func(resp *http.Response, err error) bool // 判断一次请求是否失败，默认发生错误（context 取消除外）、没有返回 Response 或者状态码 >= 500 时视为失败
+}
+
+// 熔断器打开时返回的错误
+type CircuitOpenError struct {
+	Host  string       // 熔断的 Host，如 http://example.com
+	State CircuitState // 熔断器状态
+}
+
+func (this *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is %v: %v", this.State, this.Host)
+}
+
+// 按 Host（scheme://host:port）分别统计的熔断器。
+// 通过 AddInterceptor(cb.Interceptor()) 注册为全局拦截器，或者通过 Client.SetCircuitBreaker 对单个 Client 生效。
+// 熔断器打开时请求直接返回 *CircuitOpenError，且不会重试。状态变化可以通过 OnCircuitStateChange 监听。
+type CircuitBreaker struct {
+	opt   CircuitBreakerOptions
+	hosts map[string]*hostCircuit
+	lock  sync.Mutex
+}
+
+type hostCircuit struct {
+	state       CircuitState
+	generation  int64     // 每次状态变化时加一，用来忽略状态变化之前发出的请求的结果
+	windowStart time.Time // 统计窗口的开始时间
+	requests    int       // 统计窗口内的请求数
+	failures    int       // 统计窗口内的失败数
+	openedAt    time.Time // 熔断器打开的时间
+	probes      int       // 半开状态下已经放行的探测请求数
+	successes   int       // 半开状态下成功的探测请求数
+}
+
+// 创建一个熔断器，opt 为 nil 时使用默认参数
+func NewCircuitBreaker(opt *CircuitBreakerOptions) *CircuitBreaker {
+	this := &CircuitBreaker{hosts: make(map[string]*hostCircuit)}
+	if opt != nil {
+		this.opt = *opt
+	}
+	if this.opt.FailureRatio <= 0 || this.opt.FailureRatio > 1 {
+		this.opt.FailureRatio = 0.5
+	}
+	if this.opt.MinRequests <= 0 {
+		this.opt.MinRequests = 20
+	}
+	if this.opt.Window <= 0 {
+		this.opt.Window = 10 * time.Second
+	}
+	if this.opt.OpenDuration <= 0 {
+		this.opt.OpenDuration = 5 * time.Second
+	}
+	if this.opt.HalfOpenProbes <= 0 {
+		this.opt.HalfOpenProbes = 1
+	}
+	if this.opt.IsFailure == nil {
+		this.opt.IsFailure = defaultIsFailure
+	}
+	return this
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	// 拦截器没有返回 Response 时视为失败
+	return resp == nil || resp.StatusCode >= 500
+}
+
+// 获取指定 Host（scheme://host:port）的熔断器状态
+func (this *CircuitBreaker) State(host string) CircuitState {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if c := this.hosts[host]; c != nil {
+		if c.state == CircuitOpen && time.Since(c.openedAt) >= this.opt.OpenDuration {
+			return CircuitHalfOpen
+		}
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// 获取熔断器对应的拦截器
+func (this *CircuitBreaker) Interceptor() Interceptor {
+	return func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		host := req.URL.Scheme + "://" + req.URL.Host
+		generation, err := this.allow(host)
+		if err != nil {
+			return nil, err
+		}
+		// 无论如何都要记录结果（下游 panic 时视为失败），否则半开状态下的探测名额不会被释放
+		failure := true
+		defer func() { this.record(host, generation, failure) }()
+		resp, err := next(req)
+		failure = this.opt.IsFailure(resp, err)
+		return resp, err
+	}
+}
+
+// 判断是否允许发送请求
+func (this *CircuitBreaker) allow(host string) (int64, error) {
+	this.lock.Lock()
+	c := this.hosts[host]
+	if c == nil {
+		c = &hostCircuit{windowStart: time.Now()}
+		this.hosts[host] = c
+	}
+
+	var from CircuitState
+	changed := false
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= this.opt.OpenDuration {
+		from, changed = c.state, true
+		this.setState(c, CircuitHalfOpen)
+	}
+
+	var err error
+	switch c.state {
+	case CircuitOpen:
+		err = &CircuitOpenError{Host: host, State: CircuitOpen}
+	case CircuitHalfOpen:
+		if c.probes >= this.opt.HalfOpenProbes {
+			err = &CircuitOpenError{Host: host, State: CircuitHalfOpen}
+		} else {
+			c.probes++
+		}
+	}
+	generation := c.generation
+	this.lock.Unlock()
+
+	if changed {
+		fireCircuitStateChange(host, from, CircuitHalfOpen)
+	}
+	return generation, err
+}
+
+// 记录请求结果
+func (this *CircuitBreaker) record(host string, generation int64, failure bool) {
+	this.lock.Lock()
+	c := this.hosts[host]
+	if c == nil || c.generation != generation {
+		// 请求发出之后熔断器状态已经发生了变化，忽略
+		this.lock.Unlock()
+		return
+	}
+
+	from, to := c.state, c.state
+	now := time.Now()
+	switch c.state {
+	case CircuitClosed:
+		if now.Sub(c.windowStart) >= this.opt.Window {
+			c.windowStart, c.requests, c.failures = now, 0, 0
+		}
+		c.requests++
+		if failure {
+			c.failures++
+		}
+		if c.requests >= this.opt.MinRequests && float64(c.failures) >= float64(c.requests)*this.opt.FailureRatio {
+			to = CircuitOpen
+		}
+	case CircuitHalfOpen:
+		if failure {
+			to = CircuitOpen
+		} else if c.successes++; c.successes >= this.opt.HalfOpenProbes {
+			to = CircuitClosed
+		}
+	}
+	if to != from {
+		this.setState(c, to)
+	}
+	this.lock.Unlock()
+
+	if to != from {
+		fireCircuitStateChange(host, from, to)
+	}
+}
+
+// 修改状态，并重置统计数据。调用者需持有锁
+func (this *CircuitBreaker) setState(c *hostCircuit, state CircuitState) {
+	now := time.Now()
+	c.state = state
+	c.generation++
+	c.windowStart, c.requests, c.failures = now, 0, 0
+	c.probes, c.successes = 0, 0
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+}
+
+// 设置当前 Client 使用的熔断器。等同于 AddInterceptor(cb.Interceptor())
+func (this *Client) SetCircuitBreaker(cb *CircuitBreaker) *Client {
+	if cb != nil {
+		this.AddInterceptor(cb.Interceptor())
+	}
+	return this
+}
@@ -0,0 +1,130 @@
+package httpClient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var fail, count int32 = 1, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	var changes []string
+	OnCircuitStateChange(func(host string, from, to CircuitState) {
+		changes = append(changes, from.String()+"->"+to.String())
+	})
+	defer func() { circuitStateHandler = nil }()
+
+	cb := NewCircuitBreaker(&CircuitBreakerOptions{MinRequests: 4, FailureRatio: 0.5, OpenDuration: 200 * time.Millisecond})
+	request := func() error {
+		client := Open().SetUrl(server.URL).SetRetry(3, time.Millisecond).SetCircuitBreaker(cb).Get()
+		defer client.Close()
+		return client.Error()
+	}
+
+	// 第一次请求重试 3 次，第二次请求的第一次重试时打开熔断器
+	request()
+	err := request()
+	var circuitErr *CircuitOpenError
+	if !errors.As(err, &circuitErr) || circuitErr.State != CircuitOpen {
+		t.Errorf("expect CircuitOpenError, got %v", err)
+	}
+	if n := atomic.LoadInt32(&count); n != 4 {
+		t.Errorf("expect 4 requests before circuit open, got %v", n)
+	}
+	if state := cb.State(server.URL); state != CircuitOpen {
+		t.Errorf("expect open, got %v", state)
+	}
+
+	// 熔断期间直接失败
+	begin := time.Now()
+	if err := request(); !errors.As(err, &circuitErr) {
+		t.Errorf("expect CircuitOpenError, got %v", err)
+	} else if time.Since(begin) > 50*time.Millisecond {
+		t.Errorf("should fail fast")
+	}
+
+	// 半开状态下探测失败，重新打开
+	time.Sleep(250 * time.Millisecond)
+	request()
+	if state := cb.State(server.URL); state != CircuitOpen {
+		t.Errorf("expect open, got %v", state)
+	}
+
+	// 半开状态下探测成功，关闭
+	time.Sleep(250 * time.Millisecond)
+	atomic.StoreInt32(&fail, 0)
+	if err := request(); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if state := cb.State(server.URL); state != CircuitClosed {
+		t.Errorf("expect closed, got %v", state)
+	}
+
+	expect := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(expect) {
+		t.Errorf("state changes not match: %v", changes)
+	} else {
+		for i := range expect {
+			if changes[i] != expect[i] {
+				t.Errorf("state changes not match: %v", changes)
+				break
+			}
+		}
+	}
+}
+
+func TestCircuitBreaker_ProbeRelease(t *testing.T) {
+	var mode int32 // 0: 返回 nil Response，1: panic，2: 成功
+	downstream := func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		switch atomic.LoadInt32(&mode) {
+		case 0:
+			return nil, nil
+		case 1:
+			panic("downstream panic")
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	cb := NewCircuitBreaker(&CircuitBreakerOptions{MinRequests: 1, OpenDuration: 50 * time.Millisecond})
+	request := func() (err error) {
+		defer func() {
+			if e := recover(); e != nil {
+				err = fmt.Errorf("%v", e)
+			}
+		}()
+		client := Open().SetUrl("http://127.0.0.1:1/never").SetRetryPolicy(NoRetry).SetCircuitBreaker(cb).AddInterceptor(downstream)
+		defer client.Close()
+		return client.Get().Error()
+	}
+	host := "http://127.0.0.1:1"
+
+	// 没有返回 Response 视为失败，不会 panic
+	if err := request(); err == nil || cb.State(host) != CircuitOpen {
+		t.Errorf("assert faild, err=%v, state=%v", err, cb.State(host))
+	}
+
+	// 半开状态下的探测请求 panic，视为失败并重新打开
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&mode, 1)
+	if err := request(); err == nil || cb.State(host) != CircuitOpen {
+		t.Errorf("assert faild, err=%v, state=%v", err, cb.State(host))
+	}
+
+	// 探测名额已经释放，下一次探测成功后关闭
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&mode, 2)
+	if err := request(); err != nil || cb.State(host) != CircuitClosed {
+		t.Errorf("assert faild, err=%v, state=%v", err, cb.State(host))
+	}
+}
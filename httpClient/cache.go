@@ -0,0 +1,422 @@
+package httpClient
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缓存的 Response
+type CachedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Expires    time.Time   `json:"expires"`        // 过期时间，过期之后需要向服务端重新验证（If-None-Match、If-Modified-Since）
+	Vary       string      `json:"vary,omitempty"` // 保存时 Request 中 Vary 所列出的 Header 的值，与当前请求不同时不使用该缓存
+}
+
+// 缓存的存储接口，实现时需要保证线程安全。内置 LRUCacheStore（进程内）和 RedisCacheStore（多个进程共享）两种实现。
+type CacheStore interface {
+	// 获取缓存，不存在时返回 nil。返回的对象可能会被修改，实现时需要返回副本。
+	Get(key string) *CachedResponse
+	// 保存缓存，ttl 为保留的时长（包括过期之后等待重新验证的时间）
+	Set(key string, v *CachedResponse, ttl time.Duration)
+	// 删除缓存
+	Delete(key string)
+}
+
+type CacheOptions struct {
+	Store       CacheStore                     // 缓存的存储，默认为容量 1000 的 LRUCacheStore
+	KeyFunc     func(req *http.Request) string // 计算缓存的 key，默认为 Method + " " + Url（以及 Accept-Encoding）。认证信息（Authorization、Cookie）不同的请求总是使用不同的缓存
+	Shared      bool                           // 是否是共享缓存（如多个用户共用的 RedisCacheStore）。共享缓存不保存 Cache-Control: private 以及带有 Authorization 的请求的结果，且优先使用 s-maxage
+	DefaultTTL  time.Duration                  // Response 中没有 max-age、Expires 时的缓存时长，默认 0（只保存带有 ETag、Last-Modified 的结果，每次使用前重新验证）
+	MaxStale    time.Duration                  // 过期之后继续保留用于重新验证的时长，默认 1 小时
+	MaxBodySize int                            // 可以缓存的 Response Body 的最大长度，默认 1MB
+}
+
+// 缓存状态 Header，值为 HIT（直接使用缓存）或者 REVALIDATED（服务端返回 304，使用缓存）。没有使用缓存时不设置。
+const CacheStatusHeader = "X-Cache"
+
+// 按照 HTTP 缓存规则（Cache-Control、Expires、ETag、Last-Modified）缓存 GET、HEAD 请求的结果。
+//   Response 带有 Cache-Control: no-store 时不缓存；max-age（共享缓存优先使用 s-maxage）或者 Expires 决定缓存时长；no-cache 表示每次使用前都需要重新验证
+//   缓存过期之后，使用 If-None-Match、If-Modified-Since 向服务端重新验证，服务端返回 304 时继续使用缓存
+//   Request 带有 Cache-Control: no-store 时不使用缓存；带有 no-cache 时强制重新验证
+//   Response 中的 Vary 所列出的 Header 不同的请求不共用缓存；Vary: * 时不缓存
+//   调用者自己设置了 If-None-Match、If-Modified-Since 时不使用缓存，服务端返回的 304 原样返回
+// 通过 Client.SetCache 对单个 Client 生效，或者通过 ClientConfig.Cache 对 profile 生效。多个 Client 可以共用一个 ResponseCache。
+// 缓存的 key 包含认证信息，所以通过 SetAuth、ClientConfig.Auth 设置的认证拦截器总是在缓存拦截器之前执行。
+type ResponseCache struct {
+	opt CacheOptions
+}
+
+// 创建 Response 缓存，opt 为 nil 时使用默认参数
+func NewResponseCache(opt *CacheOptions) *ResponseCache {
+	this := &ResponseCache{}
+	if opt != nil {
+		this.opt = *opt
+	}
+	if this.opt.Store == nil {
+		this.opt.Store = NewLRUCacheStore(0)
+	}
+	if this.opt.KeyFunc == nil {
+		this.opt.KeyFunc = defaultCacheKey
+	}
+	if this.opt.MaxStale <= 0 {
+		this.opt.MaxStale = time.Hour
+	}
+	if this.opt.MaxBodySize <= 0 {
+		this.opt.MaxBodySize = 1 << 20
+	}
+	return this
+}
+
+// 获取缓存对应的拦截器
+func (this *ResponseCache) Interceptor() Interceptor {
+	return func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		if req.Method != "GET" && req.Method != "HEAD" {
+			return next(req)
+		}
+		reqDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+		if _, ok := reqDirectives["no-store"]; ok {
+			return next(req)
+		}
+		if this.opt.Shared && req.Header.Get("Authorization") != "" {
+			return next(req)
+		}
+
+		key := this.opt.KeyFunc(req) + credentialKey(c, req)
+		// 调用者自己设置了条件请求时，需要的是服务端的验证结果，不使用缓存
+		conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+		var entry *CachedResponse
+		if !conditional {
+			entry = this.opt.Store.Get(key)
+		}
+		if entry != nil && entry.Vary != varyValues(req, entry.Header) {
+			entry = nil
+		}
+		if entry != nil {
+			_, noCache := reqDirectives["no-cache"]
+			if !noCache && time.Now().Before(entry.Expires) {
+				return entry.toResponse(req, "HIT"), nil
+			}
+			// 向服务端重新验证
+			if etag := entry.Header.Get("ETag"); etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+				req.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+
+		resp, err := next(req)
+		if err != nil {
+			return resp, err
+		}
+		now := time.Now()
+		if entry != nil && resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			// 使用 304 返回的 Header 更新缓存
+			for _, k := range []string{"Cache-Control", "Date", "Expires", "ETag", "Last-Modified", "Age", "Vary"} {
+				if v := resp.Header.Values(k); len(v) != 0 {
+					entry.Header[k] = v
+				}
+			}
+			this.save(key, entry, now)
+			return entry.toResponse(req, "REVALIDATED"), nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp, nil
+		}
+
+		ttl, ok := this.freshness(resp.Header, now)
+		if !ok || varyAll(resp.Header) {
+			if entry != nil {
+				this.opt.Store.Delete(key)
+			}
+			return resp, nil
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(this.opt.MaxBodySize)+1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if len(data) > this.opt.MaxBodySize {
+			// Body 太大，不缓存
+			resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(data), resp.Body), closer: resp.Body}
+			return resp, nil
+		}
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+		entry = &CachedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: data, Expires: now.Add(ttl), Vary: varyValues(req, resp.Header)}
+		this.opt.Store.Set(key, entry, this.retention(entry, ttl))
+		return resp, nil
+	}
+}
+
+// 重新计算缓存的过期时间并保存
+func (this *ResponseCache) save(key string, entry *CachedResponse, now time.Time) {
+	ttl, ok := this.freshness(entry.Header, now)
+	if !ok {
+		this.opt.Store.Delete(key)
+		return
+	}
+	entry.Expires = now.Add(ttl)
+	this.opt.Store.Set(key, entry, this.retention(entry, ttl))
+}
+
+// 缓存的保留时长：有 ETag、Last-Modified 的缓存在过期之后继续保留 MaxStale，用于重新验证
+func (this *ResponseCache) retention(entry *CachedResponse, ttl time.Duration) time.Duration {
+	if entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" {
+		return ttl + this.opt.MaxStale
+	}
+	return ttl
+}
+
+// 根据 Response Header 计算缓存时长，第二个返回值表示是否可以缓存
+func (this *ResponseCache) freshness(header http.Header, now time.Time) (time.Duration, bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return 0, false
+	}
+	if _, ok := directives["private"]; ok && this.opt.Shared {
+		return 0, false
+	}
+	validator := header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+
+	var ttl time.Duration
+	if _, ok := directives["no-cache"]; ok {
+		ttl = 0
+	} else if v, ok := directives["s-maxage"]; ok && this.opt.Shared {
+		ttl = parseSeconds(v)
+	} else if v, ok := directives["max-age"]; ok {
+		ttl = parseSeconds(v)
+	} else if v := header.Get("Expires"); v != "" {
+		if expires, err := http.ParseTime(v); err == nil {
+			date, err := http.ParseTime(header.Get("Date"))
+			if err != nil {
+				date = now
+			}
+			ttl = expires.Sub(date)
+		}
+	} else {
+		ttl = this.opt.DefaultTTL
+	}
+	if age := parseSeconds(header.Get("Age")); age > 0 {
+		ttl -= age
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, ttl > 0 || validator
+}
+
+// 默认的缓存 key：Method + " " + Url。不同的 Accept-Encoding 得到的 Response Body 不同，因此也计入 key
+func defaultCacheKey(req *http.Request) string {
+	key := req.Method + " " + req.URL.String()
+	if v := req.Header.Get("Accept-Encoding"); v != "" {
+		key += " " + v
+	}
+	return key
+}
+
+// 认证信息（Authorization、Cookie，包括会话中将要发送的 Cookie）的摘要，不同的用户不共用缓存
+func credentialKey(c *Client, req *http.Request) string {
+	auth, cookie := req.Header.Get("Authorization"), req.Header.Get("Cookie")
+	if c.Client != nil && c.Jar != nil {
+		for _, v := range c.Jar.Cookies(req.URL) {
+			cookie += "; " + v.String()
+		}
+	}
+	if auth == "" && cookie == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(auth + "\n" + cookie))
+	return " " + hex.EncodeToString(sum[:16])
+}
+
+// Request 中 Vary 所列出的 Header 的值
+func varyValues(req *http.Request, header http.Header) string {
+	var arr []string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				arr = append(arr, name+": "+strings.Join(req.Header.Values(name), ", "))
+			}
+		}
+	}
+	sort.Strings(arr)
+	return strings.Join(arr, "\n")
+}
+
+// Vary: * 表示 Response 与 Header 之外的因素有关，不能缓存
+func varyAll(header http.Header) bool {
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if strings.TrimSpace(name) == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 设置当前 Client 使用的 Response 缓存。等同于 AddInterceptor(cache.Interceptor())，
+// 但是通过 SetAuth 设置的认证拦截器总是在缓存拦截器之前执行（缓存的 key 包含认证信息）。
+func (this *Client) SetCache(cache *ResponseCache) *Client {
+	if cache != nil {
+		this.addCacheInterceptor(cache)
+	}
+	return this
+}
+
+func (this *Client) addCacheInterceptor(cache *ResponseCache) {
+	this.interceptors = append(this.interceptors, cache.Interceptor())
+	if this.cacheIndex == 0 {
+		this.cacheIndex = len(this.interceptors)
+	}
+}
+
+func (this *CachedResponse) toResponse(req *http.Request, status string) *http.Response {
+	header := this.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(CacheStatusHeader, status)
+	return &http.Response{
+		StatusCode:    this.StatusCode,
+		Status:        strconv.Itoa(this.StatusCode) + " " + http.StatusText(this.StatusCode),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(this.Body)),
+		ContentLength: int64(len(this.Body)),
+		Request:       req,
+	}
+}
+
+func (this *CachedResponse) clone() *CachedResponse {
+	v := *this
+	v.Header = this.Header.Clone()
+	return &v
+}
+
+// 解析 Cache-Control，key 为小写的指令名
+func parseCacheControl(s string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if pos := strings.Index(part, "="); pos != -1 {
+			directives[strings.ToLower(strings.TrimSpace(part[:pos]))] = strings.Trim(strings.TrimSpace(part[pos+1:]), `"`)
+		} else {
+			directives[strings.ToLower(part)] = ""
+		}
+	}
+	return directives
+}
+
+func parseSeconds(s string) time.Duration {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+type multiReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (this *multiReadCloser) Close() error {
+	return this.closer.Close()
+}
+
+// ------------------------------------------------------------------------------ LRUCacheStore
+// 进程内的 LRU 缓存，超过容量时淘汰最久没有使用的缓存
+type LRUCacheStore struct {
+	capacity int
+	list     *list.List
+	items    map[string]*list.Element
+	lock     sync.Mutex
+}
+
+type lruCacheItem struct {
+	key      string
+	value    *CachedResponse
+	expireAt time.Time
+}
+
+// 创建 LRU 缓存，capacity 为最多保存的缓存数，<=0 时默认为 1000
+func NewLRUCacheStore(capacity int) *LRUCacheStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &LRUCacheStore{capacity: capacity, list: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (this *LRUCacheStore) Get(key string) *CachedResponse {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	elem := this.items[key]
+	if elem == nil {
+		return nil
+	}
+	item := elem.Value.(*lruCacheItem)
+	if time.Now().After(item.expireAt) {
+		this.list.Remove(elem)
+		delete(this.items, key)
+		return nil
+	}
+	this.list.MoveToFront(elem)
+	return item.value.clone()
+}
+
+func (this *LRUCacheStore) Set(key string, v *CachedResponse, ttl time.Duration) {
+	if v == nil || ttl <= 0 {
+		this.Delete(key)
+		return
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	item := &lruCacheItem{key: key, value: v.clone(), expireAt: time.Now().Add(ttl)}
+	if elem := this.items[key]; elem != nil {
+		elem.Value = item
+		this.list.MoveToFront(elem)
+		return
+	}
+	this.items[key] = this.list.PushFront(item)
+	for this.list.Len() > this.capacity {
+		elem := this.list.Back()
+		this.list.Remove(elem)
+		delete(this.items, elem.Value.(*lruCacheItem).key)
+	}
+}
+
+func (this *LRUCacheStore) Delete(key string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if elem := this.items[key]; elem != nil {
+		this.list.Remove(elem)
+		delete(this.items, key)
+	}
+}
+
+// 获取缓存的数量（包括已经过期但尚未被清除的缓存）
+func (this *LRUCacheStore) Len() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.list.Len()
+}
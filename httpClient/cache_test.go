@@ -0,0 +1,180 @@
+package httpClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_CacheMaxAge(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&count, 1)
+		w.Header().Set("Cache-Control", "max-age=1")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"n":` + strconv.Itoa(int(n)) + `}`))
+	}))
+	defer server.Close()
+
+	cache := NewResponseCache(nil)
+	for i := 0; i < 3; i++ {
+		var obj struct{ N int }
+		client := Open().SetUrl(server.URL).SetCache(cache).GetJson(&obj)
+		if err := client.Error(); err != nil || obj.N != 1 {
+			t.Errorf("assert faild, err=%v, obj=%v", err, obj)
+		}
+		if i > 0 && client.ResponseHeader().Get(CacheStatusHeader) != "HIT" {
+			t.Errorf("expect cache hit")
+		}
+		client.Close()
+	}
+
+	// 请求不使用缓存
+	client := Open().SetUrl(server.URL).SetCache(cache).SetHeader("Cache-Control", "no-store").Get()
+	defer client.Close()
+	if client.ResponseText() != `{"n":2}` {
+		t.Errorf("assert faild: %v", client.ResponseText())
+	}
+
+	// 缓存过期
+	time.Sleep(1100 * time.Millisecond)
+	if text := client.Reborn().SetUrl(server.URL).SetCache(cache).Get().ResponseText(); text != `{"n":3}` {
+		t.Errorf("assert faild: %v", text)
+	}
+	// 其他方法不缓存
+	if text := client.Reborn().SetUrl(server.URL).SetCache(cache).Post().ResponseText(); text != `{"n":4}` {
+		t.Errorf("assert faild: %v", text)
+	}
+}
+
+func TestClient_CacheRevalidate(t *testing.T) {
+	var count, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(304)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	cache := NewResponseCache(&CacheOptions{Store: NewLRUCacheStore(10)})
+	client := Open().SetCache(cache)
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		client.Reborn().SetUrl(server.URL).SetCache(cache).Get()
+		if err := client.Error(); err != nil || client.ResponseText() != "hello" || client.StatusCode != 200 {
+			t.Errorf("assert faild, err=%v, status=%v, text=%v", err, client.StatusCode, client.ResponseText())
+		}
+	}
+	if count != 3 || notModified != 2 || client.ResponseHeader().Get(CacheStatusHeader) != "REVALIDATED" {
+		t.Errorf("assert faild, count=%v, notModified=%v", count, notModified)
+	}
+}
+
+func TestClient_CacheNoStore(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("ETag", `"v1"`)
+		}
+	}))
+	defer server.Close()
+
+	if err := RegisterProfile("test-cache", &ClientConfig{Cache: NewResponseCache(&CacheOptions{Shared: true})}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	for _, path := range []string{"/", "/private"} {
+		atomic.StoreInt32(&count, 0)
+		for i := 0; i < 2; i++ {
+			Open("test-cache").SetUrl(server.URL + path).Get().Close()
+		}
+		if n := atomic.LoadInt32(&count); n != 2 {
+			t.Errorf("%v should not be cached, got %v requests", path, n)
+		}
+	}
+}
+
+func TestClient_CachePrivate(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		user, _, _ := r.BasicAuth()
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("user=" + user + " lang=" + r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	// 不同用户的缓存互相隔离（SetCache 在 SetAuth 之前调用也一样）
+	if err := RegisterProfile("test-cache-private", &ClientConfig{Cache: NewResponseCache(nil)}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	get := func(user, lang string) string {
+		client := Open("test-cache-private").SetUrl(server.URL).SetAuth(&BasicAuth{Username: user}).SetHeader("Accept-Language", lang).Get()
+		defer client.Close()
+		return client.ResponseText()
+	}
+	if alice, bob := get("alice", "zh"), get("bob", "zh"); alice != "user=alice lang=zh" || bob != "user=bob lang=zh" {
+		t.Errorf("assert faild, alice=%v, bob=%v", alice, bob)
+	}
+	// Vary 所列出的 Header 不同时不使用缓存
+	if text := get("alice", "en"); text != "user=alice lang=en" {
+		t.Errorf("assert faild: %v", text)
+	}
+	if text := get("alice", "en"); text != "user=alice lang=en" || atomic.LoadInt32(&count) != 3 {
+		t.Errorf("assert faild, text=%v, count=%v", text, count)
+	}
+}
+
+func TestClient_CacheConditional(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(304)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	cache := NewResponseCache(nil)
+	client := Open().SetUrl(server.URL).SetCache(cache).Get()
+	defer client.Close()
+	// 调用者自己设置了 If-None-Match 时，304 原样返回
+	client.Reborn().SetUrl(server.URL).SetCache(cache).SetHeader("If-None-Match", `"v1"`).Get()
+	if client.StatusCode != 304 || client.ResponseHeader().Get(CacheStatusHeader) != "" {
+		t.Errorf("assert faild, status=%v, header=%v", client.StatusCode, client.ResponseHeader())
+	}
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	store := NewLRUCacheStore(2)
+	store.Set("a", &CachedResponse{Body: []byte("a")}, time.Minute)
+	store.Set("b", &CachedResponse{Body: []byte("b")}, time.Minute)
+	store.Get("a")
+	store.Set("c", &CachedResponse{Body: []byte("c")}, time.Minute)
+	if store.Get("b") != nil || store.Get("a") == nil || store.Get("c") == nil || store.Len() != 2 {
+		t.Errorf("least recently used item should be evicted")
+	}
+
+	store.Set("d", &CachedResponse{Body: []byte("d")}, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if store.Get("d") != nil {
+		t.Errorf("expired item should be removed")
+	}
+}
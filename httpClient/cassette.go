@@ -0,0 +1,356 @@
+package httpClient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"yelo/go-util/jsonUtil"
+)
+
+// 录制/回放模式
+type CassetteMode int
+
+const (
+	CassetteReplay CassetteMode = 0 // 回放：从录制文件中查找匹配的请求并返回录制的结果
+	CassetteRecord CassetteMode = 1 // 录制：发送真实请求，并将请求和结果保存到录制文件中
+	CassetteAuto   CassetteMode = 2 // 自动：能匹配到录制的请求时回放，否则发送真实请求并录制
+)
+
+// 请求的匹配规则
+type CassetteMatch int
+
+const (
+	MatchMethod CassetteMatch = 1 << iota // 匹配 Method
+	MatchURL                              // 匹配 Url（Query 参数的顺序不影响匹配）
+	MatchBody                             // 匹配 Request Body
+)
+
+type CassetteOptions struct {
+	Mode         CassetteMode  // 录制/回放模式，默认回放
+	Match        CassetteMatch // 匹配规则，默认 MatchMethod | MatchURL
+	MatchHeaders []string      // 需要匹配的 Header
+	Strict       bool          // 回放模式下遇到没有匹配的请求时是否返回错误。为 false 时发送真实请求（但不录制）
+	// 录制时需要脱敏的 Request Header 和 Response Header（不区分大小写），nil 表示使用 DefaultRedactHeaders。
+	// 脱敏之后的值保存为 ***，所以这些 Header 出现在 MatchHeaders 中时只能匹配是否存在；回放时 Response 中对应的 Header 也为 ***。
+	RedactHeaders []string
+}
+
+// 录制/回放 HTTP 请求的工具，用于在测试中摆脱对真实接口的依赖。
+// 先以录制模式运行一次测试，将请求和结果保存到 Json 格式的录制文件中，之后以回放模式运行即可离线测试。
+// 通过 Client.UseCassette 或者 AddInterceptor(cassette.Interceptor()) 使用。
+type Cassette struct {
+	path         string
+	opt          CassetteOptions
+	redact       map[string]bool
+	interactions []*cassetteInteraction
+	lock         sync.Mutex
+}
+
+type cassetteInteraction struct {
+	Request  *cassetteRequest  `json:"request"`
+	Response *cassetteResponse `json:"response"`
+	used     bool
+}
+
+type cassetteRequest struct {
+	Method     string   `json:"method"`
+	Url        string   `json:"url"`
+	Header     []string `json:"header,omitempty"`
+	Body       string   `json:"body,omitempty"`
+	BodyBase64 string   `json:"bodyBase64,omitempty"`
+}
+
+type cassetteResponse struct {
+	StatusCode int      `json:"statusCode"`
+	Header     []string `json:"header,omitempty"`
+	Body       string   `json:"body,omitempty"`
+	BodyBase64 string   `json:"bodyBase64,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []*cassetteInteraction `json:"interactions"`
+}
+
+// 创建一个录制/回放工具，path 为录制文件的路径。回放模式下录制文件必须存在。
+func NewCassette(path string, opt *CassetteOptions) (*Cassette, error) {
+	this := &Cassette{path: path}
+	if opt != nil {
+		this.opt = *opt
+	}
+	if this.opt.Match == 0 {
+		this.opt.Match = MatchMethod | MatchURL
+	}
+	if this.opt.RedactHeaders == nil {
+		this.opt.RedactHeaders = DefaultRedactHeaders
+	}
+	this.redact = make(map[string]bool, len(this.opt.RedactHeaders))
+	for _, v := range this.opt.RedactHeaders {
+		this.redact[http.CanonicalHeaderKey(v)] = true
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && this.opt.Mode != CassetteReplay {
+			return this, nil
+		}
+		return nil, fmt.Errorf("读取录制文件失败: %v", err)
+	}
+	file := &cassetteFile{}
+	if err := jsonUtil.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("录制文件格式错误: %v", err)
+	}
+	if this.opt.Mode != CassetteRecord {
+		this.interactions = file.Interactions
+	}
+	return this, nil
+}
+
+// 获取录制/回放对应的拦截器
+func (this *Cassette) Interceptor() Interceptor {
+	return func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		key := this.newRequest(req, body)
+
+		if this.opt.Mode != CassetteRecord {
+			if resp := this.find(key); resp != nil {
+				return resp.toResponse(req), nil
+			}
+			if this.opt.Mode == CassetteReplay {
+				if this.opt.Strict {
+					return nil, fmt.Errorf("cassette: 没有匹配的请求: %v %v", req.Method, req.URL)
+				}
+				return next(req)
+			}
+		}
+
+		// 录制时不使用压缩，以便录制文件可读
+		if c.autoAcceptEncoding {
+			req.Header.Del("Accept-Encoding")
+		}
+		resp, err := next(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		record := &cassetteResponse{StatusCode: resp.StatusCode, Header: this.redactLines(headerToLines(resp.Header, nil))}
+		record.Body, record.BodyBase64 = encodeCassetteBody(data)
+		if err := this.add(&cassetteInteraction{Request: key, Response: record}); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// 获取所有录制的请求中，尚未被回放过的请求数
+func (this *Cassette) Unused() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	n := 0
+	for _, v := range this.interactions {
+		if !v.used {
+			n++
+		}
+	}
+	return n
+}
+
+// 将录制的结果保存到录制文件中。录制模式下每录制一个请求都会自动保存。
+func (this *Cassette) Save() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.save()
+}
+
+func (this *Cassette) save() error {
+	data, err := jsonUtil.MarshalIndent(&cassetteFile{Interactions: this.interactions}, "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(this.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(this.path, data, 0644)
+}
+
+func (this *Cassette) add(v *cassetteInteraction) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	v.used = true
+	this.interactions = append(this.interactions, v)
+	if err := this.save(); err != nil {
+		return fmt.Errorf("保存录制文件失败: %v", err)
+	}
+	return nil
+}
+
+// 查找匹配的请求。优先返回尚未回放过的请求，都回放过时返回最后一个匹配的请求。
+func (this *Cassette) find(key *cassetteRequest) *cassetteResponse {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	var last *cassetteInteraction
+	for _, v := range this.interactions {
+		if this.match(v.Request, key) {
+			if !v.used {
+				v.used = true
+				return v.Response
+			}
+			last = v
+		}
+	}
+	if last != nil {
+		return last.Response
+	}
+	return nil
+}
+
+func (this *Cassette) match(a, b *cassetteRequest) bool {
+	if this.opt.Match&MatchMethod != 0 && !strings.EqualFold(a.Method, b.Method) {
+		return false
+	}
+	if this.opt.Match&MatchURL != 0 && normalizeUrl(a.Url) != normalizeUrl(b.Url) {
+		return false
+	}
+	if this.opt.Match&MatchBody != 0 && (a.Body != b.Body || a.BodyBase64 != b.BodyBase64) {
+		return false
+	}
+	if len(this.opt.MatchHeaders) != 0 {
+		ha, hb := linesToHeader(a.Header), linesToHeader(b.Header)
+		for _, key := range this.opt.MatchHeaders {
+			if strings.Join(ha.Values(key), ",") != strings.Join(hb.Values(key), ",") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (this *Cassette) newRequest(req *http.Request, body []byte) *cassetteRequest {
+	header := req.Header.Clone()
+	header.Del("Accept-Encoding")
+	v := &cassetteRequest{Method: req.Method, Url: req.URL.String(), Header: this.redactLines(headerToLines(header, this.opt.MatchHeaders))}
+	v.Body, v.BodyBase64 = encodeCassetteBody(body)
+	return v
+}
+
+// 将需要脱敏的 Header 的值替换为 ***，避免把凭证保存到录制文件中
+func (this *Cassette) redactLines(lines []string) []string {
+	for i, line := range lines {
+		if pos := strings.Index(line, ":"); pos != -1 && this.redact[http.CanonicalHeaderKey(strings.TrimSpace(line[:pos]))] {
+			lines[i] = line[:pos] + ": ***"
+		}
+	}
+	return lines
+}
+
+// 设置当前 Client 使用的录制/回放工具。等同于 AddInterceptor(c.Interceptor())
+func (this *Client) UseCassette(c *Cassette) *Client {
+	if c != nil {
+		this.AddInterceptor(c.Interceptor())
+	}
+	return this
+}
+
+func (this *cassetteResponse) toResponse(req *http.Request) *http.Response {
+	var data []byte
+	if this.BodyBase64 != "" {
+		data, _ = base64.StdEncoding.DecodeString(this.BodyBase64)
+	} else {
+		data = []byte(this.Body)
+	}
+	return &http.Response{
+		StatusCode:    this.StatusCode,
+		Status:        fmt.Sprintf("%d %s", this.StatusCode, http.StatusText(this.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        linesToHeader(this.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+// 读取 Request Body，并重置 req.Body 以便后续继续读取
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// 文本内容直接保存，二进制内容保存为 base64
+func encodeCassetteBody(data []byte) (string, string) {
+	if len(data) == 0 {
+		return "", ""
+	}
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return "", base64.StdEncoding.EncodeToString(data)
+}
+
+// 将 Header 转换为 "Key: Value" 格式的数组（按 Key 排序）。keys 不为空时只转换 keys 中指定的 Header
+func headerToLines(header http.Header, keys []string) []string {
+	var lines []string
+	if len(keys) != 0 {
+		for _, key := range keys {
+			for _, val := range header.Values(key) {
+				lines = append(lines, http.CanonicalHeaderKey(key)+": "+val)
+			}
+		}
+		return lines
+	}
+	for key, vals := range header {
+		for _, val := range vals {
+			lines = append(lines, key+": "+val)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func linesToHeader(lines []string) http.Header {
+	header := make(http.Header, len(lines))
+	for _, line := range lines {
+		if pos := strings.Index(line, ":"); pos != -1 {
+			header.Add(strings.TrimSpace(line[:pos]), strings.TrimSpace(line[pos+1:]))
+		}
+	}
+	return header
+}
+
+// 将 Url 的 Query 参数排序，以便比较
+func normalizeUrl(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
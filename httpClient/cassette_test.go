@@ -0,0 +1,109 @@
+package httpClient
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","body":"` + string(data) + `","token":"` + r.Header.Get("X-Token") + `"}`))
+	}))
+
+	dir, _ := ioutil.TempDir("", "httpClient")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fixtures", "api.json")
+
+	// 录制
+	recorder, err := NewCassette(path, &CassetteOptions{Mode: CassetteRecord, Match: MatchMethod | MatchURL | MatchBody, MatchHeaders: []string{"X-Token"}})
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	for _, token := range []string{"a", "b"} {
+		client := Open().SetUrl(server.URL+"/users?b=2&a=1").SetHeader("X-Token", token).SetBody("hello").UseCassette(recorder).Post()
+		if err := client.Error(); err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+		client.Close()
+	}
+	serverUrl := server.URL
+	server.Close()
+
+	// 回放
+	player, err := NewCassette(path, &CassetteOptions{Match: MatchMethod | MatchURL | MatchBody, MatchHeaders: []string{"X-Token"}, Strict: true})
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	var obj struct {
+		Path  string `json:"path"`
+		Body  string `json:"body"`
+		Token string `json:"token"`
+	}
+	client := Open().SetUrl(serverUrl+"/users?a=1&b=2").SetHeader("X-Token", "b").SetBody("hello").UseCassette(player).PostJson(&obj)
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if obj.Path != "/users" || obj.Body != "hello" || obj.Token != "b" {
+		t.Errorf("assert faild: %+v", obj)
+	}
+	client.Close()
+	if n := player.Unused(); n != 1 {
+		t.Errorf("expect 1 unused interaction, got %v", n)
+	}
+
+	// 严格模式下未匹配的请求返回错误
+	client = Open().SetUrl(serverUrl+"/users?a=1&b=2").SetHeader("X-Token", "c").SetBody("hello").UseCassette(player).Post()
+	if client.Error() == nil {
+		t.Errorf("expect error for unmatched request")
+	}
+	client.Close()
+
+	// 回放模式下录制文件必须存在
+	if _, err := NewCassette(filepath.Join(dir, "not-exist.json"), nil); err == nil {
+		t.Errorf("expect error for missing file")
+	}
+}
+
+func TestCassette_RedactHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session-secret"})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "httpClient")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api.json")
+	recorder, err := NewCassette(path, &CassetteOptions{Mode: CassetteRecord})
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	auth := &BasicAuth{Username: "tom", Password: "password-secret"}
+	client := Open().SetUrl(server.URL).SetHeader("Cookie", "sid=cookie-secret").SetAuth(auth).UseCassette(recorder).Get()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	client.Close()
+
+	// 录制文件中不包含凭证，但是保留了 Header 的名称
+	data, _ := ioutil.ReadFile(path)
+	basic := base64.StdEncoding.EncodeToString([]byte("tom:password-secret"))
+	for _, secret := range []string{basic, "password-secret", "cookie-secret", "session-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("secret should not be recorded: %v", secret)
+		}
+	}
+	if !strings.Contains(string(data), "Authorization: ***") || !strings.Contains(string(data), "Set-Cookie: ***") {
+		t.Errorf("assert faild: %s", data)
+	}
+}
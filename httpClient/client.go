@@ -2,25 +2,26 @@
 // 对 HTTP Client 的进一步封装
 //   1、简化各种参数的设置
 //   2、针对返回 Json 结构的 Restful Api，封装了更加简化的接口
-//   3、内部实现自动重试机制，调用接口时如果发生（除超时之外的）错误则自动重试，以消除服务抖动的问题
+//   3、内部实现自动重试机制，调用接口时如果发生（除超时之外的）错误则按照重试策略（RetryPolicy）自动重试，以消除服务抖动的问题
+//   4、自动解压 gzip、deflate 格式的 Response Body，并支持根据 Content-Type 选择解码器
+//   5、支持 context.Context，可以取消正在进行的请求，context 的 deadline 覆盖包括重试在内的整个请求过程
 // ------------------------------------------------------------------------------
 package httpClient
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
-	"errors"
 	"fmt"
-	"github.com/emirpasic/gods/lists/singlylinkedlist"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"yelo/go-util/arrUtil"
 	"yelo/go-util/jsonUtil"
@@ -30,20 +31,35 @@ import (
 // HTTP 客户端类，用户封装 HTTP 请求
 type Client struct {
 	*http.Client
-	fromPool     bool              // 是否是连接池中的连接
-	method       string            // http method
-	url          string            // http url
-	body         interface{}       // http post body
-	headers      map[string]string // http headers
-	cookie       []*http.Cookie    // cookie
-	ctx          interface{}       // context
-	ignoreEvents bool
-	idle         bool
-	retry        int
-	retryInterel time.Duration
-	err          error
-	responseData []byte
-	responseText string
+	fromPool           bool              // 是否是连接池中的连接
+	method             string            // http method
+	url                string            // http url
+	query              url.Values        // query 参数
+	queryReplace       map[string]bool   // 需要覆盖 url 中同名参数的 query 参数（通过 SetQuery 设置）
+	pathParams         map[string]string // url 模板中的路径参数
+	body               interface{}       // http post body
+	headers            map[string]string // http headers
+	cookie             []*http.Cookie    // cookie
+	ctx                interface{}       // context
+	reqCtx             context.Context   // 请求使用的 context.Context
+	ignoreEvents       bool
+	idle               int32       // 是否已经归还到连接池（1 表示已经归还），通过 atomic 读写
+	retryPolicy        RetryPolicy // 重试策略
+	attempts           int         // 上一次请求实际发起的请求次数（包含重试）
+	err                error
+	responseData       []byte
+	responseText       string
+	responseHeader     http.Header
+	responseBody       io.ReadCloser // 流式请求时，尚未读取的 Response Body
+	interceptors       []Interceptor // 只对当前 Client 生效的拦截器
+	cacheIndex         int           // 第一个缓存拦截器在 interceptors 中的下标 +1，0 表示没有。认证拦截器总是插入到缓存拦截器之前
+	profile            *profile      // 使用的客户端配置
+	profileName        string        // 打开 Client 时指定的 profile 名称
+	compressThreshold  int           // Request Body 的压缩阈值
+	autoAcceptEncoding bool          // 本次请求是否由 httpClient 自动设置了 Accept-Encoding（需要自动解压 Response Body）
+	rateLimitWait      time.Duration // 上一次请求（包括重试）因为限流而等待的总时间
+	statsLock          sync.Mutex    // 保护拦截器中修改的统计数据（对冲请求时拦截器会被并发调用）
+	debug              *DebugLogger  // 调试日志，nil 表示不输出
 
 	StatusCode int
 	Status     string
@@ -59,69 +75,34 @@ type RequestParam struct {
 }
 
 var (
-	DefaultRetry        = 3
-	DefaultRetryIntevel = 100 * time.Millisecond
-	DefaultContentType  = ""
-	defaultKeepAlive    = 120 * time.Second
+	DefaultContentType = ""
 
-	idleClients        = singlylinkedlist.New() // 空闲的 client 列表，用来管理空闲连接
+	idleClients        = sync.Pool{New: func() interface{} { return &Client{Client: &http.Client{}, fromPool: true} }} // 空闲的 client，长时间未被使用的 client 会被 GC 回收
 	requestPathPattern = regexp.MustCompile("://.*?(/[^?#]+)")
 	asciiPattern       = regexp.MustCompile("^[\\x00-\\xff]+$")
-	lock               sync.Mutex
 )
 
-func SetDefaultTimeout(timeout time.Duration, keepAlive ...time.Duration) {
-	var keepAliveVal time.Duration
-	if len(keepAlive) != 0 && keepAlive[0] <= 0 {
-		keepAliveVal = keepAlive[0]
-	} else {
-		keepAliveVal = 120 * time.Second
-	}
-	defaultKeepAlive = keepAliveVal
-
-	http.DefaultClient.Timeout = timeout
-	transport := http.DefaultTransport.(*http.Transport)
-	transport.ResponseHeaderTimeout = timeout
-	transport.DialContext = (&net.Dialer{
-		Timeout:   timeout,
-		KeepAlive: keepAliveVal,
-	}).DialContext
-}
-
 // ------------------------------------------------------------------------------ getter & setter
 // （从空闲的连接池中）打开一个 HTTP 客户端连接。请在使用完毕之后调用 Close 释放资源。
-func Open() *Client {
-	var client *Client
-
-	lock.Lock()
-	defer lock.Unlock()
-
-	it := idleClients.Iterator()
-	for it.Next() {
-		if client := it.Value().(*Client); client.idle {
-			client.Reborn().idle = false
-			return client
-		}
+// 参数 profile 指定使用哪个客户端配置（参考 RegisterProfile），为空时使用默认配置。
+// 如果指定的配置不存在，则之后的请求都会返回错误。
+func Open(profile ...string) *Client {
+	var name string
+	if len(profile) != 0 {
+		name = profile[0]
 	}
 
-	client = (&Client{Client: http.DefaultClient, fromPool: true}).Reborn().setIdle(false)
-	idleClients.Add(client)
-
-	return client
+	client := idleClients.Get().(*Client)
+	atomic.StoreInt32(&client.idle, 0)
+	client.profile, client.profileName = getProfile(name), name
+	return client.Reborn()
 }
 
-func (this *Client) setIdle(v bool) *Client {
-	this.idle = v
-	return this
-}
-
-// 关闭 HTTP 客户端连接（归还给连接池）。
+// 关闭 HTTP 客户端连接（归还给连接池）。重复调用 Close 是安全的，但 Close 之后不应该再使用该 Client。
 func (this *Client) Close() {
-	if this.fromPool {
-		lock.Lock()
-		defer lock.Unlock()
+	if atomic.CompareAndSwapInt32(&this.idle, 0, 1) && this.fromPool {
+		idleClients.Put(this)
 	}
-	this.idle = true
 }
 
 // 关闭 HTTP 客户端连接（归还给连接池），同时返回上一次 Request 返回的结果 (ResponseData, error)
@@ -141,20 +122,33 @@ func (this *Client) CloseAndError() error {
 // 重置所有参数（但不关闭连接），这样可以连续多次使用。
 func (this *Client) Reborn() *Client {
 	this.ctx = nil
+	this.reqCtx = nil
 	this.method = ""
 	this.url = ""
+	this.query = nil
+	this.queryReplace = nil
+	this.pathParams = nil
 	this.body = nil
+	this.cookie = nil
 	this.headers = make(map[string]string)
 	if DefaultContentType != "" {
 		this.headers["Content-Type"] = DefaultContentType
 	}
-	this.retry = DefaultRetry
-	this.retryInterel = DefaultRetryIntevel
+	this.retryPolicy = DefaultRetryPolicy
+	this.attempts = 0
+	this.rateLimitWait = 0
 	this.err = nil
 	this.responseData = nil
 	this.responseText = ""
+	this.responseHeader = nil
+	this.responseBody = nil
+	this.interceptors = nil
+	this.cacheIndex = 0
+	this.compressThreshold = 0
+	this.autoAcceptEncoding = false
 	this.ignoreEvents = false
-	this.Timeout = http.DefaultClient.Timeout
+	this.debug = nil
+	this.resetProfile()
 	return this
 }
 
@@ -170,6 +164,18 @@ func (this *Client) SetContext(ctx interface{}) *Client {
 	return this
 }
 
+// 获取请求使用的 context.Context，未设置时返回 nil
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) Ctx() context.Context {
+	return this.reqCtx
+}
+
+// 设置请求使用的 context.Context。context 取消或者超时后，正在进行的请求会被中断，并且不再重试。
+func (this *Client) SetCtx(ctx context.Context) *Client {
+	this.reqCtx = ctx
+	return this
+}
+
 // 获取上一次请求的 Method
 // 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
 func (this *Client) Method() string {
@@ -181,7 +187,7 @@ func (this *Client) SetMethod(method string) *Client {
 	return this
 }
 
-// 获取上一次请求的 Url
+// 获取上一次请求的 Url（通过 SetUrl 设置的原始值，实际请求的 Url 请使用 RequestUrl 获取）
 // 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
 func (this *Client) Url() string {
 	return this.url
@@ -195,31 +201,19 @@ func (this *Client) SetUrl(v string) *Client {
 // 获取上一次请求的 RequestPath
 // 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
 func (this *Client) RequestPath() string {
-	if arr := requestPathPattern.FindStringSubmatch(this.url); len(arr) == 2 {
+	requestUrl := this.RequestUrl()
+	if arr := requestPathPattern.FindStringSubmatch(requestUrl); len(arr) == 2 {
 		return arr[1]
 	} else {
-		return this.url
+		return requestUrl
 	}
 }
 
-// 设置超时参数。默认的超时参数可以通过 http.TimeFormat、http.DefaultTransport 全局设置
+// 设置当前 Client 整个请求（包括读取 Response Body）的超时时间，调用 Reborn 或者 Close 之后恢复为 profile 中的配置。
+// 只影响当前 Client，不会修改其他 Client 或者 profile 的配置。
+// 参数 keepAlive 已不再使用（连接池由 profile 管理），请通过 ClientConfig 设置。
 func (this *Client) SetTimeout(timeout time.Duration, keepAlive ...time.Duration) *Client {
-	var keepAliveVal time.Duration
-	if len(keepAlive) != 0 && keepAlive[0] <= 0 {
-		keepAliveVal = keepAlive[0]
-	} else {
-		keepAliveVal = defaultKeepAlive
-	}
-
 	this.Timeout = timeout
-	switch t := this.Transport.(type) {
-	case *http.Transport:
-		t.ResponseHeaderTimeout = timeout
-		t.DialContext = (&net.Dialer{
-			Timeout:   this.Timeout,
-			KeepAlive: keepAliveVal,
-		}).DialContext
-	}
 	return this
 }
 
@@ -229,7 +223,13 @@ func (this *Client) Body() interface{} {
 	return this.body
 }
 
-// 设置 Request Body
+// 设置 Request Body。支持以下类型：
+//   string、[]byte: 原样发送
+//   url.Values: 以 application/x-www-form-urlencoded 格式发送，参考 SetForm
+//   *Multipart: 以 multipart/form-data 格式发送，参考 NewMultipart
+//   BodyFunc: 每次（重试）请求时调用该函数重新创建 body，适用于发送大文件等不便于一次性读入内存的数据
+//   io.Reader: 直接读取 io.Reader 发送。如果没有实现 io.Seeker，则请求失败时无法重试
+//   其他类型: 按照 Json 格式序列化后发送
 func (this *Client) SetBody(v interface{}) *Client {
 	this.body = v
 	return this
@@ -299,6 +299,19 @@ func (this *Client) setHeader(key string, a interface{}) *Client {
 	return this
 }
 
+// 从 headers 中获取指定的 Header，key 不区分大小写
+func getHeader(headers map[string]string, key string) string {
+	if v, ok := headers[key]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
 func (this *Client) SetContentType(v string) *Client {
 	this.setHeader("Content-Type", v)
 	return this
@@ -309,6 +322,7 @@ func (this *Client) SetContentTypeJson() *Client {
 	return this
 }
 
+// 添加请求的 Cookie，调用 Reborn（包括从连接池中重新打开）时清除。需要在多次请求之间保持服务端设置的 Cookie 时请使用 Session（参考 SetSession）。
 func (this *Client) AddCookie(a ...*http.Cookie) {
 	this.cookie = append(this.cookie, a...)
 }
@@ -316,11 +330,12 @@ func (this *Client) AddCookie(a ...*http.Cookie) {
 // 获取上一次请求的请求参数
 // 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
 func (this *Client) RequestParams() *RequestParam {
-	params := &RequestParam{Method: this.method, Api: this.url}
-	if pos := strings.Index(this.url, "://"); pos != -1 {
-		if v, err := url.Parse(this.url); err == nil {
+	requestUrl := this.RequestUrl()
+	params := &RequestParam{Method: this.method, Api: requestUrl}
+	if pos := strings.Index(requestUrl, "://"); pos != -1 {
+		if v, err := url.Parse(requestUrl); err == nil {
 			params.Host = v.Scheme + "://" + v.Host
-			params.Api = this.url[len(params.Host):]
+			params.Api = requestUrl[len(params.Host):]
 		}
 	}
 	if len(this.headers) != 0 {
@@ -336,49 +351,144 @@ func (this *Client) RequestParams() *RequestParam {
 		sort.Strings(params.Cookie)
 	}
 	if this.body != nil && this.body != "" {
-		params.Body = this.body
+		switch t := this.body.(type) {
+		case *Multipart:
+			params.Body = t.String()
+		case url.Values:
+			params.Body = t.Encode()
+		case BodyFunc, io.Reader:
+			params.Body = "(stream)"
+		default:
+			params.Body = this.body
+		}
 	}
 	return params
 }
 
+// 按照 RequestParam 设置请求参数（Method、Url、Header、Body、Cookie），与 RequestParams 相对应。
+func (this *Client) SetRequestParam(p *RequestParam) *Client {
+	if p == nil {
+		return this
+	}
+	if p.Method != "" {
+		this.SetMethod(p.Method)
+	}
+	this.SetUrl(p.Host + p.Api)
+	if this.headers == nil {
+		this.headers = make(map[string]string)
+	}
+	for k, v := range p.Header {
+		this.headers[k] = v
+	}
+	if p.Body != nil {
+		this.SetBody(p.Body)
+	}
+	for _, v := range p.Cookie {
+		if pos := strings.Index(v, ";"); pos != -1 {
+			v = v[:pos]
+		}
+		if pos := strings.Index(v, "="); pos > 0 {
+			this.AddCookie(&http.Cookie{Name: strings.TrimSpace(v[:pos]), Value: strings.TrimSpace(v[pos+1:])})
+		}
+	}
+	return this
+}
+
 // 设置是否忽略 HTTP 事件。 忽略后的请求将不会被发送给通过 OnRequest 设置的回调函数。默认不忽略。
 func (this *Client) IgnoreEvents() *Client {
 	this.ignoreEvents = true
 	return this
 }
 
-// 设置重试策略
+// 设置重试策略：最多请求 retry 次（包含第一次请求），每次重试前固定等待 intervel。等同于 SetRetryPolicy(NewFixedRetry(retry, intervel))
 func (this *Client) SetRetry(retry int, intervel time.Duration) *Client {
-	this.retry, this.retryInterel = retry, intervel
+	this.retryPolicy = NewFixedRetry(retry, intervel)
 	return this
 }
 
+// 设置重试策略，nil 表示不重试
+func (this *Client) SetRetryPolicy(policy RetryPolicy) *Client {
+	this.retryPolicy = policy
+	return this
+}
+
+// 获取上一次请求实际发起的请求次数（包含重试）
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) Attempts() int {
+	return this.attempts
+}
+
 // （使用已经设置好的 Method、Url、Body、Header 等）发起 HTTP 请求，并等待处理结果。
+// 请求失败时根据重试策略（RetryPolicy）决定是否重试。
 func (this *Client) Request() *Client {
+	this.execute(false)
+	return this
+}
+
+// 发起 HTTP 请求，请求失败时根据重试策略（RetryPolicy）决定是否重试。
+// 如果 stream 为 true，则请求成功之后不读取 Response Body，而是保存在 responseBody 中，由调用者负责读取和关闭。
+func (this *Client) execute(stream bool) {
 	begin := time.Now().UnixNano()
-	this.doRequest()
-	for i := 1; i < this.retry && (this.err != nil || this.StatusCode <= 0 || this.StatusCode >= 400); i++ {
-		if this.err == nil {
-			if this.StatusCode > 0 && this.StatusCode < 400 {
-				// 如果调用成功，不重试
-				break
-			} else if this.StatusCode == 408 || this.StatusCode == 504 {
-				// 如果是超时，不重试
-				break
-			}
-		} else {
-			if -1 != strings.Index(this.err.Error(), "timeout awaiting response headers") || -1 != strings.Index(this.err.Error(), "Client.Timeout exceeded while awaiting header") {
-				// 如果是超时，不重试
-				break
-			}
+	ctx := this.reqCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if this.fromPool && this.profile == nil {
+		this.err = fmt.Errorf("profile %v 不存在", this.profileName)
+		this.attempts = 0
+		if !this.ignoreEvents {
+			fireRequest(this, this.err, 0)
+		}
+		return
+	}
+	this.rateLimitWait = 0
+	for this.attempts = 1; ; this.attempts++ {
+		this.doRequest(ctx, stream)
+		if this.err == nil && this.StatusCode > 0 && this.StatusCode < 400 {
+			// 如果调用成功，不重试
+			break
+		} else if ctx.Err() != nil || this.retryPolicy == nil {
+			// context 已经结束，不重试
+			break
+		} else if !this.bodyReplayable() {
+			// body 无法重复读取，不重试
+			break
+		} else if retry, wait := this.retryPolicy.ShouldRetry(this, this.attempts); !retry || !sleepCtx(ctx, wait) {
+			break
 		}
-		time.Sleep(this.retryInterel)
-		this.doRequest()
+	}
+	if this.err != nil && ctx.Err() == context.DeadlineExceeded {
+		// 请求因 context 超时而失败，返回 *TimeoutError，Err 为 context 结束的原因
+		this.err = &TimeoutError{Method: this.method, URL: this.RequestUrl(), Attempts: this.attempts, Elapsed: time.Duration(time.Now().UnixNano() - begin), Err: context.Cause(ctx)}
+	} else if this.err != nil && ctx.Err() != nil {
+		// 请求因 context 被取消而失败，返回 context 结束的原因
+		this.err = context.Cause(ctx)
+	} else if this.err != nil {
+		this.err = this.wrapError(this.err, time.Duration(time.Now().UnixNano()-begin))
 	}
 	if !this.ignoreEvents {
 		fireRequest(this, this.err, float32(time.Now().UnixNano()-begin)/1000000)
 	}
-	return this
+}
+
+// 使用参数指定的 context.Context 发起 HTTP 请求，并等待处理结果。 等同于 SetCtx(ctx).Request()
+func (this *Client) RequestCtx(ctx context.Context) *Client {
+	return this.SetCtx(ctx).Request()
+}
+
+// 等待指定的时间，如果在等待过程中 context 结束则立即返回 false
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // 获取上一次 Request 返回的结果，(ResponseData, error)
@@ -402,11 +512,11 @@ func (this *Client) Success(status ...int) (bool, error) {
 	}
 	if len(status) == 0 {
 		if this.StatusCode < 200 || this.StatusCode >= 400 {
-			return false, errors.New(this.Status)
+			return false, this.newHTTPError()
 		}
 	} else {
 		if this.StatusCode != 0 && arrUtil.IndexOfInt(status, this.StatusCode) == -1 {
-			return false, errors.New(this.Status)
+			return false, this.newHTTPError()
 		}
 	}
 	return true, nil
@@ -418,6 +528,12 @@ func (this *Client) ResponseData() []byte {
 	return this.responseData
 }
 
+// 获取上一次 Request 返回的 Response Header
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) ResponseHeader() http.Header {
+	return this.responseHeader
+}
+
 // 获取上一次 Request 返回的 ResponseText
 // 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
 func (this *Client) ResponseText() string {
@@ -427,38 +543,80 @@ func (this *Client) ResponseText() string {
 	return this.responseText
 }
 
-func (this *Client) doRequest() ([]byte, error) {
+// 发起一次 HTTP 请求（不包含重试）
+func (this *Client) doRequest(ctx context.Context, stream bool) {
 	this.err = nil
 	this.responseData = nil
 	this.responseText = ""
+	this.responseHeader = nil
+	this.responseBody = nil
 	this.StatusCode = -1
 	this.Status = "Send Request Error"
 
+	req, err := this.newRequest(ctx)
+	if err != nil {
+		this.err = err
+		return
+	}
+
+	begin := time.Now()
+	resp, err := this.roundTrip(req)
+	if err != nil {
+		this.err = err
+		this.debug.log(this, req, nil, nil, false, err, time.Since(begin))
+		return
+	}
+	decompressResponse(req, resp, this.autoAcceptEncoding)
+
+	this.responseHeader = resp.Header
+	if resp.StatusCode != 0 {
+		this.Status = resp.Status
+		this.StatusCode = resp.StatusCode
+	}
+	if this.StatusCode < 200 || this.StatusCode >= 400 {
+		// 读取 Response Body 之后再创建 HTTPError，以便保存 Body
+		defer func() { this.err = this.newHTTPError() }()
+	}
+	if stream && this.StatusCode >= 200 && this.StatusCode < 400 {
+		this.responseBody = resp.Body
+		this.debug.log(this, req, resp, nil, true, nil, time.Since(begin))
+		return
+	}
+	defer resp.Body.Close()
+
+	// read response
+	var data []byte
+	if data, err = ioutil.ReadAll(resp.Body); err != nil {
+		this.err = err
+	}
+	this.responseData = data
+	this.debug.log(this, req, resp, data, false, this.err, time.Since(begin))
+}
+
+// 根据已经设置好的 Method、Url、Body、Header 等创建 http.Request。每次（重试）请求都会重新创建 Request Body。
+func (this *Client) newRequest(ctx context.Context) (*http.Request, error) {
 	if this.method == "" {
 		this.method = "GET"
 	}
 
-	var body io.Reader = nil
-	if this.body != nil {
-		switch t := this.body.(type) {
-		case string:
-			body = strings.NewReader(t)
-		case []byte:
-			body = bytes.NewReader(t)
-		default:
-			data, err := jsonUtil.Marshal(this.body)
-			if err != nil {
-				this.err = fmt.Errorf("body 序列化失败: %v", err)
-				return this.responseData, this.err
-			}
-			body = bytes.NewReader(data)
-		}
+	body, contentType, getBody, err := this.newRequestBody()
+	if err != nil {
+		return nil, err
+	}
+	body, compressed, err := this.compressBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("body 压缩失败: %v", err)
 	}
 
-	req, err := http.NewRequest(this.method, this.url, body)
+	req, err := http.NewRequestWithContext(ctx, this.method, this.RequestUrl(), body)
 	if err != nil {
-		this.err = err
-		return this.responseData, this.err
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	if getBody != nil {
+		req.GetBody = getBody
 	}
 
 	if len(this.headers) != 0 {
@@ -466,29 +624,80 @@ func (this *Client) doRequest() ([]byte, error) {
 			req.Header.Set(k, v)
 		}
 	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	this.autoAcceptEncoding = false
+	if req.Header.Get("Accept-Encoding") == "" && (this.profile == nil || !this.profile.config.DisableCompression) {
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		this.autoAcceptEncoding = true
+	}
 	if len(this.cookie) != 0 {
 		for _, v := range this.cookie {
 			req.AddCookie(v)
 		}
 	}
+	return req, nil
+}
 
-	resp, err := this.Do(req)
-	if err != nil {
-		this.err = err
-		return this.responseData, this.err
+// 创建 Request Body。
+// 返回值:
+//   body: Request Body
+//   contentType: body 要求的 Content-Type（如 multipart/form-data 的 boundary），为空表示使用 Header 中设置的值
+//   getBody: 用来重新创建 body 的函数，为空表示使用 http.NewRequest 自动设置的值
+func (this *Client) newRequestBody() (body io.Reader, contentType string, getBody func() (io.ReadCloser, error), err error) {
+	if this.body == nil {
+		return nil, "", nil, nil
 	}
-	defer resp.Body.Close()
-
-	// read response
-	this.responseData, this.err = ioutil.ReadAll(resp.Body)
-	if resp.StatusCode != 0 {
-		this.Status = resp.Status
-		this.StatusCode = resp.StatusCode
-		if this.StatusCode < 200 || this.StatusCode >= 400 {
-			this.err = errors.New(this.Status)
+	switch t := this.body.(type) {
+	case string:
+		return strings.NewReader(t), "", nil, nil
+	case []byte:
+		return bytes.NewReader(t), "", nil, nil
+	case url.Values:
+		return strings.NewReader(t.Encode()), "application/x-www-form-urlencoded", nil, nil
+	case *Multipart:
+		getBody = func() (io.ReadCloser, error) { return t.reader(), nil }
+		return t.reader(), t.ContentType(), getBody, nil
+	case BodyFunc:
+		getBody = func() (io.ReadCloser, error) {
+			r, err := t()
+			if err != nil {
+				return nil, err
+			}
+			return toReadCloser(r), nil
+		}
+		rc, err := getBody()
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("创建 body 失败: %v", err)
 		}
+		return rc, "", getBody, nil
+	case io.Reader:
+		if seeker, ok := t.(io.Seeker); ok && this.attempts > 1 {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, "", nil, fmt.Errorf("body 重置失败: %v", err)
+			}
+		}
+		return ioutil.NopCloser(t), "", nil, nil
+	default:
+		data, err := jsonUtil.Marshal(this.body)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("body 序列化失败: %v", err)
+		}
+		return bytes.NewReader(data), "", nil, nil
+	}
+}
+
+// Request Body 是否可以在重试时重复读取
+func (this *Client) bodyReplayable() bool {
+	if r, ok := this.body.(io.Reader); ok {
+		_, ok = r.(io.Seeker)
+		return ok
 	}
-	return this.responseData, err
+	return true
 }
 
 // 使用 POST 方式发起 HTTP 请求并等待处理结果。 等同于 SetMethod("POST").Request()
@@ -501,14 +710,24 @@ func (this *Client) Get() *Client {
 	return this.SetMethod("GET").Request()
 }
 
+// 使用 POST 方式、参数指定的 context.Context 发起 HTTP 请求并等待处理结果。 等同于 SetCtx(ctx).SetMethod("POST").Request()
+func (this *Client) PostCtx(ctx context.Context) *Client {
+	return this.SetCtx(ctx).Post()
+}
+
+// 使用 GET 方式、参数指定的 context.Context 发起 HTTP 请求并等待处理结果。 等同于 SetCtx(ctx).SetMethod("GET").Request()
+func (this *Client) GetCtx(ctx context.Context) *Client {
+	return this.SetCtx(ctx).Get()
+}
+
 // （使用已经设置好的 Method、Url、Body、Header 等）发起 HTTP 请求，并尝试将返回的 ResponseData 按照 Json 格式反序列化到参数指定的对象中。
-// 如果 Json 反序列化出错，则可以通过 error() 获取错误对象。
+// 如果 Json 反序列化出错，则可以通过 error() 获取错误对象（*DecodeError）。
 func (this *Client) RequestJson(obj interface{}) *Client {
 	this.Request()
 	if this.err == nil && obj != nil {
 		err := jsonUtil.Unmarshal(this.responseData, obj)
 		if err != nil {
-			this.err = fmt.Errorf("json decode error, responseText: %s, err=%v", this.ResponseText(), err)
+			this.err = newDecodeError(this.responseHeader.Get("Content-Type"), this.responseData, err)
 		}
 	}
 	return this
@@ -524,6 +743,21 @@ func (this *Client) GetJson(obj interface{}) *Client {
 	return this.SetMethod("GET").RequestJson(obj)
 }
 
+// 使用参数指定的 context.Context 发起 HTTP 请求，并尝试将返回的 ResponseData 按照 Json 格式反序列化到参数指定的对象中。 等同于 SetCtx(ctx).RequestJson(obj)
+func (this *Client) RequestJsonCtx(ctx context.Context, obj interface{}) *Client {
+	return this.SetCtx(ctx).RequestJson(obj)
+}
+
+// 使用 POST 方式、参数指定的 context.Context 发起 HTTP 请求，并尝试将返回的 ResponseData 按照 Json 格式反序列化到参数指定的对象中。
+func (this *Client) PostJsonCtx(ctx context.Context, obj interface{}) *Client {
+	return this.SetCtx(ctx).PostJson(obj)
+}
+
+// 使用 GET 方式、参数指定的 context.Context 发起 HTTP 请求，并尝试将返回的 ResponseData 按照 Json 格式反序列化到参数指定的对象中。
+func (this *Client) GetJsonCtx(ctx context.Context, obj interface{}) *Client {
+	return this.SetCtx(ctx).GetJson(obj)
+}
+
 func (this *RequestParam) SetHost(v string) *RequestParam {
 	this.Host = v
 	return this
@@ -0,0 +1,83 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_RequestCtxCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(3 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	cause := errors.New("caller gone")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel(cause)
+	}()
+
+	begin := time.Now()
+	client := Open().SetUrl(server.URL).GetCtx(ctx)
+	defer client.Close()
+	if took := time.Since(begin); took > time.Second {
+		t.Errorf("request not cancelled in time, took %v", took)
+	}
+	if err := client.Error(); err != cause {
+		t.Errorf("expect cancel cause, got %v", err)
+	}
+}
+
+func TestClient_RequestCtxDeadlineCoversRetry(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	client := Open().SetUrl(server.URL).SetRetry(10, 100*time.Millisecond).GetCtx(ctx)
+	defer client.Close()
+	if n := atomic.LoadInt32(&count); n < 1 || n > 3 {
+		t.Errorf("expect retry stopped by deadline, got %v attempts", n)
+	}
+	if err := client.Error(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+	// context 超时同样返回 *TimeoutError
+	var timeoutErr *TimeoutError
+	if !errors.As(client.Error(), &timeoutErr) || timeoutErr.Method != "GET" || timeoutErr.URL != server.URL || timeoutErr.Attempts < 1 || timeoutErr.Elapsed < 100*time.Millisecond {
+		t.Errorf("expect *TimeoutError, got %#v", client.Error())
+	}
+}
+
+func TestClient_GetJsonCtx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"abc","count":"12"}`))
+	}))
+	defer server.Close()
+
+	var obj struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	client := Open().SetUrl(server.URL).GetJsonCtx(context.Background(), &obj)
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if obj.Name != "abc" || obj.Count != 12 {
+		t.Errorf("assert faild: %+v", obj)
+	}
+}
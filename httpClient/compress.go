@@ -0,0 +1,114 @@
+package httpClient
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 设置 Request Body 的压缩阈值，body 的长度达到该值时使用 gzip 压缩（并设置 Content-Encoding: gzip），<=0 表示不压缩。
+// 只对长度已知的 body（string、[]byte、表单、Json 等）生效，BodyFunc、io.Reader、Multipart 不会被压缩。
+// 请确认服务端支持解压 gzip 格式的 Request Body。
+func (this *Client) SetCompressThreshold(n int) *Client {
+	this.compressThreshold = n
+	return this
+}
+
+// 如果 body 的长度达到了压缩阈值，则返回压缩之后的 body
+func (this *Client) compressBody(body io.Reader) (io.Reader, bool, error) {
+	if this.compressThreshold <= 0 || body == nil {
+		return body, false, nil
+	}
+
+	var size int
+	switch t := body.(type) {
+	case *bytes.Reader:
+		size = t.Len()
+	case *strings.Reader:
+		size = t.Len()
+	default:
+		return body, false, nil
+	}
+	if size < this.compressThreshold {
+		return body, false, nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size/2))
+	writer := gzip.NewWriter(buf)
+	if _, err := io.Copy(writer, body); err != nil {
+		return nil, false, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, false, err
+	}
+	return bytes.NewReader(buf.Bytes()), true, nil
+}
+
+// 解压 Response Body。只有在 httpClient 自动设置了 Accept-Encoding 时才会解压，调用者自己设置了 Accept-Encoding 的则原样返回。
+func decompressResponse(req *http.Request, resp *http.Response, autoAccept bool) {
+	if !autoAccept || resp.Body == nil || req.Method == "HEAD" {
+		return
+	}
+
+	body := resp.Body
+	var reader io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		reader = &lazyReader{open: func() (io.Reader, error) { return gzip.NewReader(body) }, body: body}
+	case "deflate":
+		reader = &lazyReader{open: func() (io.Reader, error) { return newDeflateReader(body) }, body: body}
+	default:
+		return
+	}
+
+	resp.Body = reader
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// deflate 格式有 zlib 封装（RFC 1950）和裸 deflate（RFC 1951）两种，服务端的实现不统一，根据数据头判断
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// 在第一次读取时才创建解压器，避免服务端返回空 body 时出错
+type lazyReader struct {
+	open   func() (io.Reader, error)
+	body   io.ReadCloser
+	reader io.Reader
+	err    error
+}
+
+func (this *lazyReader) Read(p []byte) (int, error) {
+	if this.reader == nil && this.err == nil {
+		if this.reader, this.err = this.open(); this.err == io.EOF {
+			this.reader, this.err = bytes.NewReader(nil), nil
+		}
+	}
+	if this.err != nil {
+		return 0, this.err
+	}
+	return this.reader.Read(p)
+}
+
+func (this *lazyReader) Close() error {
+	if closer, ok := this.reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return this.body.Close()
+}
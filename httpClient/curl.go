@@ -0,0 +1,414 @@
+package httpClient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"yelo/go-util/jsonUtil"
+)
+
+// 将请求参数转换为等价的 curl 命令（参数按照 shell 的规则转义），便于调试时在命令行中重现请求。
+func (this *RequestParam) ToCurl() string {
+	return this.toCurl(nil)
+}
+
+// 将当前设置的请求参数转换为等价的 curl 命令，参考 RequestParam.ToCurl。
+// multipart/form-data 格式的 Body 转换为 -F 参数；BodyFunc、io.Reader 格式的 Body 无法转换，会被忽略。
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) ToCurl() string {
+	params := this.RequestParams()
+	var form []string
+	switch t := this.body.(type) {
+	case *Multipart:
+		params.Body = nil
+		for _, part := range t.parts {
+			if part.open == nil {
+				form = append(form, "-F", shellQuote(part.field+"="+part.value))
+			} else if part.path != "" {
+				form = append(form, "-F", shellQuote(part.field+"=@"+part.path))
+			} else {
+				form = append(form, "-F", shellQuote(part.field+"=@"+part.filename))
+			}
+		}
+	case BodyFunc:
+		params.Body = nil
+	default:
+		if params.Body == "(stream)" {
+			params.Body = nil
+		}
+	}
+	return params.toCurl(form)
+}
+
+func (this *RequestParam) toCurl(form []string) string {
+	method := strings.ToUpper(this.Method)
+	if method == "" {
+		method = "GET"
+	}
+	arr := []string{"curl"}
+	if method != "GET" {
+		arr = append(arr, "-X", method)
+	}
+	arr = append(arr, shellQuote(this.Host+this.Api))
+
+	var body string
+	hasBody := this.Body != nil && this.Body != ""
+	if hasBody {
+		switch t := this.Body.(type) {
+		case string:
+			body = t
+		case []byte:
+			body = string(t)
+		case url.Values:
+			body = t.Encode()
+		default:
+			body, _ = jsonUtil.MarshalToString(t)
+			// 与发送请求时相同，Json 格式的 Body 需要指定 Content-Type，否则 curl 按照表单格式发送
+			if getHeader(this.Header, "Content-Type") == "" {
+				arr = append(arr, "-H", shellQuote("Content-Type: application/json"))
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(this.Header))
+	for key := range this.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		arr = append(arr, "-H", shellQuote(key+": "+this.Header[key]))
+	}
+	if len(this.Cookie) != 0 {
+		cookies := make([]string, len(this.Cookie))
+		for i, v := range this.Cookie {
+			if pos := strings.Index(v, ";"); pos != -1 {
+				v = v[:pos]
+			}
+			cookies[i] = v
+		}
+		arr = append(arr, "-b", shellQuote(strings.Join(cookies, "; ")))
+	}
+	arr = append(arr, form...)
+
+	if hasBody {
+		arr = append(arr, "--data-raw", shellQuote(body))
+	}
+	return strings.Join(arr, " ")
+}
+
+// 解析 curl 命令（如浏览器开发者工具中 "Copy as cURL" 得到的命令），转换为请求参数，之后可以通过 Client.SetRequestParam 发起请求。
+// 支持的参数：-X/--request、-H/--header、-d/--data/--data-raw/--data-binary/--data-ascii/--data-urlencode、-b/--cookie、-u/--user、-A/--user-agent、-e/--referer、-G/--get、-I/--head、--url，
+// 以及 --compressed、-k/--insecure、-L/--location、-s/--silent、-m/--max-time 等不影响请求内容的参数（忽略）。
+// 不认识的长参数（如 --http2）视为没有值的参数并忽略；-X、-H、-d、-m、-o 等短参数的值可以紧跟在参数之后，如 -XPOST、-m5。
+func ParseCurl(cmd string) (*RequestParam, error) {
+	args, err := splitShellArgs(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("不是 curl 命令")
+	}
+
+	params := &RequestParam{Header: make(map[string]string)}
+	var rawUrl string
+	var data []string
+	var getData, head bool
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		// 处理 --data=xxx 格式的参数
+		var val string
+		hasVal := false
+		if strings.HasPrefix(arg, "--") {
+			if pos := strings.Index(arg, "="); pos != -1 {
+				arg, val, hasVal = arg[:pos], arg[pos+1:], true
+			}
+		} else if len(arg) > 2 && arg[0] == '-' && strings.Contains("XHdbuAemo", arg[1:2]) {
+			arg, val, hasVal = arg[:2], arg[2:], true
+		}
+		next := func() (string, error) {
+			if hasVal {
+				return val, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("参数 %v 缺少值", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch arg {
+		case "-X", "--request":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			params.Method = strings.ToUpper(val)
+		case "-H", "--header":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			if pos := strings.Index(val, ":"); pos > 0 {
+				params.Header[strings.TrimSpace(val[:pos])] = strings.TrimSpace(val[pos+1:])
+			}
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw", "--data-urlencode":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			if arg != "--data-raw" && strings.HasPrefix(val, "@") {
+				return nil, fmt.Errorf("不支持从文件读取 body: %v", val)
+			}
+			if arg == "--data-urlencode" {
+				if pos := strings.Index(val, "="); pos != -1 {
+					val = val[:pos+1] + url.QueryEscape(val[pos+1:])
+				} else {
+					val = url.QueryEscape(val)
+				}
+			}
+			data = append(data, val)
+		case "-b", "--cookie":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			if !strings.Contains(val, "=") {
+				return nil, fmt.Errorf("不支持从文件读取 cookie: %v", val)
+			}
+			for _, v := range strings.Split(val, ";") {
+				if v = strings.TrimSpace(v); v != "" {
+					params.Cookie = append(params.Cookie, v)
+				}
+			}
+		case "-u", "--user":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			username, password := val, ""
+			if pos := strings.Index(val, ":"); pos != -1 {
+				username, password = val[:pos], val[pos+1:]
+			}
+			params.Header["Authorization"] = basicAuthHeader(username, password)
+		case "-A", "--user-agent":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			params.Header["User-Agent"] = val
+		case "-e", "--referer":
+			if val, err = next(); err != nil {
+				return nil, err
+			}
+			params.Header["Referer"] = val
+		case "--url":
+			if rawUrl, err = next(); err != nil {
+				return nil, err
+			}
+		case "-G", "--get":
+			getData = true
+		case "-I", "--head":
+			head = true
+		case "--compressed", "-k", "--insecure", "-L", "--location", "-s", "--silent", "-S", "--show-error", "-v", "--verbose", "-i", "--include", "-g", "--globoff":
+		case "-o", "--output", "-m", "--max-time", "--connect-timeout", "--retry", "--retry-delay", "--retry-max-time", "--max-redirs", "--limit-rate",
+			"-x", "--proxy", "--cacert", "--cert", "--key", "--resolve", "-w", "--write-out", "-D", "--dump-header", "-c", "--cookie-jar":
+			if _, err = next(); err != nil {
+				return nil, err
+			}
+		default:
+			if strings.HasPrefix(arg, "--") {
+				// 其他不影响请求内容的长参数（如 --http2、--fail），忽略
+				continue
+			}
+			if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+				// 合并在一起的短参数，如 -sSL
+				for j := 1; j < len(arg); j++ {
+					switch arg[j] {
+					case 'G':
+						getData = true
+					case 'I':
+						head = true
+					case 'k', 'L', 's', 'S', 'v', 'i', 'g':
+					default:
+						return nil, fmt.Errorf("不支持的参数: %v", arg)
+					}
+				}
+				continue
+			}
+			if rawUrl == "" {
+				rawUrl = arg
+			}
+		}
+	}
+	if rawUrl == "" {
+		return nil, fmt.Errorf("缺少 Url")
+	}
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "http://" + rawUrl
+	}
+
+	if len(data) != 0 {
+		body := strings.Join(data, "&")
+		if getData {
+			if strings.Contains(rawUrl, "?") {
+				rawUrl += "&" + body
+			} else {
+				rawUrl += "?" + body
+			}
+		} else {
+			params.Body = body
+			if params.Method == "" {
+				params.Method = "POST"
+			}
+			if getHeader(params.Header, "Content-Type") == "" {
+				params.Header["Content-Type"] = "application/x-www-form-urlencoded"
+			}
+		}
+	}
+	if params.Method == "" {
+		if head {
+			params.Method = "HEAD"
+		} else {
+			params.Method = "GET"
+		}
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("Url 格式错误: %v", err)
+	}
+	if u.User != nil {
+		if getHeader(params.Header, "Authorization") == "" {
+			// u.User.String() 是转义之后的格式，需要分别取出（反转义之后的）用户名和密码
+			password, _ := u.User.Password()
+			params.Header["Authorization"] = basicAuthHeader(u.User.Username(), password)
+		}
+		u.User = nil
+	}
+	params.Host = u.Scheme + "://" + u.Host
+	params.Api = u.String()[len(params.Host):]
+	if len(params.Header) == 0 {
+		params.Header = nil
+	}
+	return params, nil
+}
+
+// 生成 Basic 认证的 Authorization Header，与 http.Request.SetBasicAuth 相同
+func basicAuthHeader(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// 按照 shell 的规则为参数加上单引号
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// 按照 shell 的规则拆分命令行参数，支持单引号、双引号、$'...'、反斜杠转义以及续行
+func splitShellArgs(s string) ([]string, error) {
+	var args []string
+	var buf strings.Builder
+	inArg := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, buf.String())
+				buf.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] == '\n' {
+					// 续行
+					continue
+				}
+				if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+					continue
+				}
+				buf.WriteByte(s[i])
+				inArg = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("单引号不匹配")
+			}
+			buf.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := readAnsiCString(s[i+2:], &buf)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) != -1 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				buf.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("双引号不匹配")
+			}
+			inArg = true
+		default:
+			buf.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+// 读取 $'...' 格式的字符串（不包括开头的 $'），返回读取的长度（包括结尾的单引号）
+func readAnsiCString(s string, buf *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"', 'a': '\a', 'b': '\b', 'f': '\f', 'v': '\v', 'e': 0x1b}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i + 1, nil
+		}
+		if c != '\\' || i+1 >= len(s) {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		if v, ok := escapes[s[i]]; ok {
+			buf.WriteByte(v)
+		} else if s[i] == 'x' || s[i] == 'u' {
+			size := 2
+			if s[i] == 'u' {
+				size = 4
+			}
+			j := i + 1
+			for j < len(s) && j < i+1+size && strings.IndexByte("0123456789abcdefABCDEF", s[j]) != -1 {
+				j++
+			}
+			var v rune
+			fmt.Sscanf(s[i+1:j], "%x", &v)
+			if s[i] == 'x' {
+				buf.WriteByte(byte(v))
+			} else {
+				buf.WriteRune(v)
+			}
+			i = j - 1
+		} else {
+			buf.WriteByte('\\')
+			buf.WriteByte(s[i])
+		}
+	}
+	return 0, fmt.Errorf("单引号不匹配")
+}
@@ -0,0 +1,311 @@
+package httpClient
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"yelo/go-util/log"
+)
+
+var (
+	// 默认需要脱敏的 Header
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", HMACSignatureHeader}
+	// 默认需要脱敏的字段，字段名包含其中任意一项即脱敏
+	DefaultRedactFields = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "access_key"}
+)
+
+type DebugOptions struct {
+	Logger        log.Logger // 输出日志的 Logger，nil 表示不输出
+	MaxBodySize   int        // 日志中 Request Body 和 Response Body 的最大长度，超过的部分被截断，默认 1024，<0 表示不输出 Body
+	RedactHeaders []string   // 需要脱敏的 Header（不区分大小写），nil 表示使用 DefaultRedactHeaders
+	RedactFields  []string   // 需要脱敏的 Json 字段、表单字段、Query 参数以及 Header，名称（不区分大小写）包含其中任意一项即脱敏，nil 表示使用 DefaultRedactFields
+	Mask          string     // 脱敏之后显示的内容，默认为 ***
+}
+
+// 调试日志，通过 log.Logger 的 Debug 级别输出每次请求（包括重试）的 Method、Url、Header、Request Body、状态码以及 Response Body
+type DebugLogger struct {
+	opt     DebugOptions
+	headers map[string]bool
+	fields  []string
+}
+
+func NewDebugLogger(opt *DebugOptions) *DebugLogger {
+	realOpt := DebugOptions{}
+	if opt != nil {
+		realOpt = *opt
+	}
+	if realOpt.Logger == nil {
+		realOpt.Logger = log.EmptyLogger()
+	}
+	if realOpt.MaxBodySize == 0 {
+		realOpt.MaxBodySize = 1024
+	}
+	if realOpt.RedactHeaders == nil {
+		realOpt.RedactHeaders = DefaultRedactHeaders
+	}
+	if realOpt.RedactFields == nil {
+		realOpt.RedactFields = DefaultRedactFields
+	}
+	if realOpt.Mask == "" {
+		realOpt.Mask = "***"
+	}
+
+	d := &DebugLogger{opt: realOpt, headers: make(map[string]bool)}
+	for _, v := range realOpt.RedactHeaders {
+		d.headers[http.CanonicalHeaderKey(v)] = true
+	}
+	for _, v := range realOpt.RedactFields {
+		if v != "" {
+			d.fields = append(d.fields, strings.ToLower(v))
+		}
+	}
+	return d
+}
+
+// 设置当前 Client 的调试日志，nil 表示不输出调试日志
+func (this *Client) SetDebug(d *DebugLogger) *Client {
+	this.debug = d
+	return this
+}
+
+// 输出一次请求的调试日志。stream 表示 Response Body 由调用者读取，不输出其内容
+func (this *DebugLogger) log(c *Client, req *http.Request, resp *http.Response, body []byte, stream bool, err error, elapsed time.Duration) {
+	if this == nil {
+		return
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[httpClient] %v %v (attempt %v, %v)\n", req.Method, this.redactUrl(req.URL.String()), c.attempts, elapsed.Round(time.Millisecond))
+	this.writeHeader(&sb, "> ", req.Header)
+	if this.opt.MaxBodySize > 0 {
+		if text := this.requestBody(c, req); text != "" {
+			sb.WriteString("> \n")
+			this.writeBody(&sb, "> ", text)
+		}
+	}
+
+	if resp == nil {
+		fmt.Fprintf(&sb, "< %v\n", err)
+	} else {
+		fmt.Fprintf(&sb, "< %v\n", resp.Status)
+		this.writeHeader(&sb, "< ", resp.Header)
+		if this.opt.MaxBodySize > 0 {
+			var text string
+			if stream {
+				text = "(stream)"
+			} else {
+				text = this.bodyText(resp.Header.Get("Content-Type"), body)
+			}
+			if text != "" {
+				sb.WriteString("< \n")
+				this.writeBody(&sb, "< ", text)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(&sb, "< %v\n", err)
+		}
+	}
+	this.opt.Logger.Debug("%v", strings.TrimSuffix(sb.String(), "\n"))
+}
+
+func (this *DebugLogger) writeHeader(sb *strings.Builder, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			if this.redactHeader(k) {
+				v = this.opt.Mask
+			}
+			sb.WriteString(prefix + k + ": " + v + "\n")
+		}
+	}
+}
+
+func (this *DebugLogger) writeBody(sb *strings.Builder, prefix, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		sb.WriteString(prefix + line + "\n")
+	}
+}
+
+// 读取 Request Body 用于输出日志（通过 GetBody 重新创建，不影响已经发出的请求）。
+// 只输出已经在内存中的 Body，BodyFunc、io.Reader 等流式的 Body 重新创建时可能有副作用，只输出 (stream)
+func (this *DebugLogger) requestBody(c *Client, req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	switch c.body.(type) {
+	case *Multipart:
+		return "(multipart)"
+	case BodyFunc, io.Reader:
+		return "(stream)"
+	}
+	contentType := req.Header.Get("Content-Type")
+	if req.GetBody == nil {
+		return "(stream)"
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Sprintf("(%v)", err)
+	}
+	defer body.Close()
+	var reader io.Reader = body
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		if reader, err = gzip.NewReader(body); err != nil {
+			return fmt.Sprintf("(%v)", err)
+		}
+	}
+	data, _ := ioutil.ReadAll(io.LimitReader(reader, int64(this.opt.MaxBodySize)+1))
+	return this.bodyText(contentType, data)
+}
+
+// 对 Body 脱敏并截断
+func (this *DebugLogger) bodyText(contentType string, data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	truncated := len(data) > this.opt.MaxBodySize
+	if truncated {
+		data = data[:this.opt.MaxBodySize]
+		// 避免截断半个字符
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return "(binary)"
+	}
+
+	text := string(data)
+	contentType = strings.ToLower(contentType)
+	trimmed := strings.TrimSpace(text)
+	if strings.Contains(contentType, "json") || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		text = this.redactJson(text)
+	} else if strings.Contains(contentType, "x-www-form-urlencoded") {
+		text = this.redactQuery(text)
+	}
+	if truncated {
+		text += "...(truncated)"
+	}
+	return text
+}
+
+func (this *DebugLogger) redactUrl(rawUrl string) string {
+	pos := strings.Index(rawUrl, "?")
+	if pos == -1 {
+		return rawUrl
+	}
+	return rawUrl[:pos+1] + this.redactQuery(rawUrl[pos+1:])
+}
+
+// 对 a=1&b=2 格式的参数脱敏，保持参数原有的顺序
+func (this *DebugLogger) redactQuery(query string) string {
+	items := strings.Split(query, "&")
+	for i, item := range items {
+		if pos := strings.Index(item, "="); pos != -1 && this.redactField(item[:pos]) {
+			items[i] = item[:pos+1] + this.opt.Mask
+		}
+	}
+	return strings.Join(items, "&")
+}
+
+func (this *DebugLogger) redactHeader(name string) bool {
+	return this.headers[http.CanonicalHeaderKey(name)] || this.redactField(name)
+}
+
+func (this *DebugLogger) redactField(name string) bool {
+	name = strings.ToLower(name)
+	for _, v := range this.fields {
+		if strings.Contains(name, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// 对 Json 中名称需要脱敏的字段的值（包括对象、数组）脱敏，允许 Json 被截断
+func (this *DebugLogger) redactJson(text string) string {
+	if len(this.fields) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for i := 0; i < len(text); {
+		if text[i] != '"' {
+			i++
+			continue
+		}
+		end := skipJsonString(text, i)
+		j := skipJsonSpace(text, end)
+		if j >= len(text) || text[j] != ':' || end-i < 2 || !this.redactField(text[i+1:end-1]) {
+			i = end
+			continue
+		}
+		j = skipJsonSpace(text, j+1)
+		sb.WriteString(text[last:j])
+		sb.WriteString(`"` + this.opt.Mask + `"`)
+		i = skipJsonValue(text, j)
+		last = i
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func skipJsonSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) != -1 {
+		i++
+	}
+	return i
+}
+
+// 跳过从 i（双引号）开始的字符串，返回字符串之后的位置
+func skipJsonString(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '\\' {
+			j++
+		} else if s[j] == '"' {
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// 跳过从 i 开始的一个值（字符串、对象、数组或者数字等），返回值之后的位置
+func skipJsonValue(s string, i int) int {
+	if i >= len(s) {
+		return i
+	}
+	switch s[i] {
+	case '"':
+		return skipJsonString(s, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(s); {
+			switch s[j] {
+			case '"':
+				j = skipJsonString(s, j)
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return j + 1
+				}
+			}
+			j++
+		}
+		return len(s)
+	}
+	j := i
+	for j < len(s) && strings.IndexByte(",}] \t\r\n", s[j]) == -1 {
+		j++
+	}
+	return j
+}
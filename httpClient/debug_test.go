@@ -0,0 +1,121 @@
+package httpClient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (this *testLogger) Error(format string, a ...interface{}) {}
+func (this *testLogger) Warn(format string, a ...interface{})  {}
+func (this *testLogger) Info(format string, a ...interface{})  {}
+func (this *testLogger) Debug(format string, a ...interface{}) {
+	this.lines = append(this.lines, fmt.Sprintf(format, a...))
+}
+
+func TestClient_Debug(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "sid=abc")
+		w.Write([]byte(`{"access_token": "tk-123", "expires": 3600, "user": {"name": "tom", "password": null}, "data": "` + strings.Repeat("x", 100) + `"}`))
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+	debug := NewDebugLogger(&DebugOptions{Logger: logger, MaxBodySize: 120})
+	client := Open().SetDebug(debug).SetUrl(server.URL+"/login?a=1&token=abc").
+		SetHeader("Authorization", "Bearer secret-token").SetHeader("X-Api-Token", "xyz").
+		SetBody(`{"username":"tom","password":"p@ss\"word"}`).Post()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	if len(logger.lines) != 1 {
+		t.Errorf("assert faild: %v", logger.lines)
+		return
+	}
+	text := logger.lines[0]
+	for _, v := range []string{"secret-token", "xyz", "abc", "p@ss", "tk-123", `"password": null`} {
+		if strings.Contains(text, v) {
+			t.Errorf("%v should be redacted: %v", v, text)
+		}
+	}
+	for _, v := range []string{
+		"POST " + server.URL + "/login?a=1&token=*** ",
+		"> Authorization: ***",
+		"> X-Api-Token: ***",
+		`> {"username":"tom","password":"***"}`,
+		"< 200 OK",
+		"< Set-Cookie: ***",
+		`"access_token": "***", "expires": 3600, "user": {"name": "tom", "password": "***"}`,
+		"...(truncated)",
+	} {
+		if !strings.Contains(text, v) {
+			t.Errorf("log should contains %v: %v", v, text)
+		}
+	}
+
+	// 表单以及自定义规则
+	logger.lines = nil
+	debug = NewDebugLogger(&DebugOptions{Logger: logger, RedactHeaders: []string{}, RedactFields: []string{"card"}, Mask: "<hidden>"})
+	client.Reborn().SetDebug(debug).SetUrl(server.URL).SetHeader("Authorization", "Basic abc").
+		SetHeader("Content-Type", "application/x-www-form-urlencoded").SetBody("name=tom&card_no=1234&password=1").Post()
+	if len(logger.lines) != 1 {
+		t.Errorf("assert faild: %v", logger.lines)
+		return
+	}
+	text = logger.lines[0]
+	for _, v := range []string{"> Authorization: Basic abc", "> name=tom&card_no=<hidden>&password=1", `"access_token": "tk-123"`} {
+		if !strings.Contains(text, v) {
+			t.Errorf("log should contains %v: %v", v, text)
+		}
+	}
+
+	// 未开启调试日志
+	logger.lines = nil
+	client.Reborn().SetUrl(server.URL).Get()
+	if len(logger.lines) != 0 {
+		t.Errorf("assert faild: %v", logger.lines)
+	}
+}
+
+func TestClient_DebugBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token": {"value": "tk-123", "scope": ["a", "}"]}, "secrets": ["s1", "s2"], "name": "tom"}`))
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+	var calls int32
+	client := Open().SetDebug(NewDebugLogger(&DebugOptions{Logger: logger})).SetUrl(server.URL).SetMethod("PUT").
+		SetBodyFunc(func() (io.Reader, error) {
+			atomic.AddInt32(&calls, 1)
+			return strings.NewReader("body"), nil
+		}).Request()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	// 流式的 Body 不会为了输出日志而重新创建
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("BodyFunc should be called once, got %v", n)
+	}
+	text := strings.Join(logger.lines, "\n")
+	// 对象、数组类型的值整体脱敏
+	for _, v := range []string{"> (stream)", `{"token": "***", "secrets": "***", "name": "tom"}`} {
+		if !strings.Contains(text, v) {
+			t.Errorf("log should contains %v: %v", v, text)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package httpClient
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+	"yelo/go-util/jsonUtil"
+)
+
+// 将 Response Body 反序列化到 obj 中的函数
+type Decoder func(data []byte, obj interface{}) error
+
+var (
+	// 无法根据 Content-Type 找到对应的解码器时使用的默认解码器
+	DefaultDecoder Decoder = JsonDecoder
+
+	decoders = map[string]Decoder{
+		"application/json":                  JsonDecoder,
+		"text/json":                         JsonDecoder,
+		"application/xml":                   XmlDecoder,
+		"text/xml":                          XmlDecoder,
+		"application/x-www-form-urlencoded": FormDecoder,
+	}
+	decoderLock sync.RWMutex
+)
+
+// 注册 Content-Type 对应的解码器（会覆盖已经存在的解码器）。contentType 不区分大小写，且不包含 charset 等参数，如 application/json。
+func RegisterDecoder(contentType string, d Decoder) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" || d == nil {
+		return
+	}
+	decoderLock.Lock()
+	defer decoderLock.Unlock()
+	decoders[contentType] = d
+}
+
+// 获取 Content-Type 对应的解码器。
+// 先按照完整的 media type 查找，找不到时按照后缀（如 application/problem+json 的 +json）查找，仍然找不到时返回 DefaultDecoder。
+func GetDecoder(contentType string) Decoder {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+
+	decoderLock.RLock()
+	defer decoderLock.RUnlock()
+	if d := decoders[mediaType]; d != nil {
+		return d
+	}
+	if pos := strings.LastIndex(mediaType, "+"); pos != -1 {
+		if d := decoders["application/"+mediaType[pos+1:]]; d != nil {
+			return d
+		}
+	}
+	return DefaultDecoder
+}
+
+// Json 解码器（使用 jsonUtil，支持弱类型解析）
+func JsonDecoder(data []byte, obj interface{}) error {
+	return jsonUtil.Unmarshal(data, obj)
+}
+
+// Xml 解码器
+func XmlDecoder(data []byte, obj interface{}) error {
+	return xml.Unmarshal(data, obj)
+}
+
+// 表单（application/x-www-form-urlencoded）解码器。
+// obj 为 *url.Values 时直接赋值；否则先将表单转换为 map（只有一个值的字段转换为字符串，多个值的字段转换为字符串数组），再按照 Json 规则反序列化到 obj 中。
+func FormDecoder(data []byte, obj interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	if t, ok := obj.(*url.Values); ok {
+		*t = values
+		return nil
+	}
+	dict := make(map[string]interface{}, len(values))
+	for key, vals := range values {
+		if len(vals) == 1 {
+			dict[key] = vals[0]
+		} else {
+			dict[key] = vals
+		}
+	}
+	data, err = json.Marshal(dict)
+	if err != nil {
+		return err
+	}
+	return jsonUtil.Unmarshal(data, obj)
+}
+
+// （使用已经设置好的 Method、Url、Body、Header 等）发起 HTTP 请求，并根据 Response 的 Content-Type 选择解码器（参考 GetDecoder），将 ResponseData 反序列化到参数指定的对象中。
+// 如果反序列化出错，则可以通过 Error() 获取错误对象（*DecodeError）。
+func (this *Client) RequestDecode(obj interface{}) *Client {
+	this.Request()
+	if this.err == nil && obj != nil {
+		contentType := this.responseHeader.Get("Content-Type")
+		if err := GetDecoder(contentType)(this.responseData, obj); err != nil {
+			this.err = newDecodeError(contentType, this.responseData, err)
+		}
+	}
+	return this
+}
+
+// 使用 POST 方式发起 HTTP 请求，并根据 Response 的 Content-Type 将 ResponseData 反序列化到参数指定的对象中。 等同于 SetMethod("POST").RequestDecode(obj)
+func (this *Client) PostDecode(obj interface{}) *Client {
+	return this.SetMethod("POST").RequestDecode(obj)
+}
+
+// 使用 GET 方式发起 HTTP 请求，并根据 Response 的 Content-Type 将 ResponseData 反序列化到参数指定的对象中。 等同于 SetMethod("GET").RequestDecode(obj)
+func (this *Client) GetDecode(obj interface{}) *Client {
+	return this.SetMethod("GET").RequestDecode(obj)
+}
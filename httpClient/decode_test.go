@@ -0,0 +1,116 @@
+package httpClient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_RequestDecode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+			w.Write([]byte(`{"name":"abc","count":"3"}`))
+		case "/xml":
+			w.Header().Set("Content-Type", "text/xml")
+			w.Write([]byte(`<obj><name>abc</name><count>3</count></obj>`))
+		case "/form":
+			w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+			w.Write([]byte(`name=abc&count=3`))
+		case "/custom":
+			w.Header().Set("Content-Type", "text/x-custom")
+			w.Write([]byte(`abc|3`))
+		}
+	}))
+	defer server.Close()
+
+	type Obj struct {
+		Name  string `json:"name" xml:"name"`
+		Count int    `json:"count" xml:"count"`
+	}
+	RegisterDecoder("Text/X-Custom", func(data []byte, obj interface{}) error {
+		arr := strings.Split(string(data), "|")
+		obj.(*Obj).Name, obj.(*Obj).Count = arr[0], len(arr[1])+2
+		return nil
+	})
+
+	for _, path := range []string{"/json", "/xml", "/form", "/custom"} {
+		var obj Obj
+		client := Open().SetUrl(server.URL + path).GetDecode(&obj)
+		if err := client.Error(); err != nil {
+			t.Errorf("%v: error occured: %v", path, err)
+		} else if obj.Name != "abc" || obj.Count != 3 {
+			t.Errorf("%v: assert faild: %+v", path, obj)
+		}
+		client.Close()
+	}
+}
+
+func TestClient_Decompress(t *testing.T) {
+	content := strings.Repeat("hello world ", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), r.URL.Path[1:]) {
+			w.Write([]byte(content))
+			return
+		}
+		var writer io.WriteCloser
+		switch r.URL.Path {
+		case "/gzip":
+			writer = gzip.NewWriter(w)
+		case "/deflate":
+			if r.URL.Query().Get("raw") != "" {
+				writer, _ = flate.NewWriter(w, flate.DefaultCompression)
+			} else {
+				writer = zlib.NewWriter(w)
+			}
+		}
+		w.Header().Set("Content-Encoding", r.URL.Path[1:])
+		writer.Write([]byte(content))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/gzip", "/deflate", "/deflate?raw=1"} {
+		client := Open().SetUrl(server.URL + path).Get()
+		if err := client.Error(); err != nil {
+			t.Errorf("%v: error occured: %v", path, err)
+		} else if client.ResponseText() != content {
+			t.Errorf("%v: content not match: %v", path, client.ResponseText())
+		}
+		client.Close()
+	}
+}
+
+func TestClient_CompressRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, _ = gzip.NewReader(r.Body)
+		}
+		data, _ := ioutil.ReadAll(reader)
+		w.Write([]byte(r.Header.Get("Content-Encoding") + "|"))
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	large := string(bytes.Repeat([]byte("a"), 2048))
+	client := Open().SetUrl(server.URL).SetBody(large).SetCompressThreshold(1024).Post()
+	if text := client.ResponseText(); text != "gzip|"+large {
+		t.Errorf("large body should be compressed: %v", text[:10])
+	}
+	client.Close()
+
+	client = Open().SetUrl(server.URL).SetBody("small").SetCompressThreshold(1024).Post()
+	if text := client.ResponseText(); text != "|small" {
+		t.Errorf("small body should not be compressed: %v", text)
+	}
+	client.Close()
+}
@@ -0,0 +1,159 @@
+package httpClient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const unixSocketPrefix = "unix://"
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// 根据 ClientConfig 中的 Resolve、DNSCacheTTL、UnixSocket 创建 Transport 使用的 DialContext
+func newDialContext(cfg *ClientConfig) (dialFunc, error) {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+	if cfg.UnixSocket != "" {
+		path := strings.TrimPrefix(cfg.UnixSocket, unixSocketPrefix)
+		if path == "" {
+			return nil, fmt.Errorf("UnixSocket 不能为空")
+		}
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}, nil
+	}
+	if len(cfg.Resolve) == 0 && cfg.DNSCacheTTL <= 0 {
+		return dialer.DialContext, nil
+	}
+
+	resolve := make(map[string]string, len(cfg.Resolve))
+	for k, v := range cfg.Resolve {
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		if k == "" || v == "" || v == unixSocketPrefix {
+			return nil, fmt.Errorf("Resolve 配置错误: %v => %v", k, v)
+		}
+		resolve[k] = v
+	}
+	var cache *dnsCache
+	if cfg.DNSCacheTTL > 0 {
+		cache = newDNSCache(cfg.DNSCacheTTL)
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		target := resolveAddr(resolve, addr)
+		if strings.HasPrefix(target, unixSocketPrefix) {
+			return dialer.DialContext(ctx, "unix", strings.TrimPrefix(target, unixSocketPrefix))
+		}
+		host, port, err := net.SplitHostPort(target)
+		if cache == nil || err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, target)
+		}
+
+		ips, err := cache.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		// 与 net.Dialer 一样，依次尝试每个地址
+		for _, ip := range ips {
+			if (network == "tcp4" && strings.Contains(ip, ":")) || (network == "tcp6" && !strings.Contains(ip, ":")) {
+				continue
+			}
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			} else if ctx.Err() != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = &net.AddrError{Err: "no suitable address found", Addr: host}
+		}
+		return nil, err
+	}, nil
+}
+
+// 按照 Resolve 配置替换连接的地址。先匹配 host:port，再匹配 host；替换的地址不包含端口时使用原来的端口
+func resolveAddr(resolve map[string]string, addr string) string {
+	if len(resolve) == 0 {
+		return addr
+	}
+	addr = strings.ToLower(addr)
+	if v, ok := resolve[addr]; ok {
+		return withPort(v, addr)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if v, ok := resolve[host]; ok {
+		return withPort(v, addr)
+	} else if v, ok := resolve["["+host+"]"]; ok {
+		return withPort(v, addr)
+	}
+	return addr
+}
+
+func withPort(target, addr string) string {
+	if strings.HasPrefix(target, unixSocketPrefix) {
+		return target
+	}
+	if net.ParseIP(target) == nil {
+		if _, _, err := net.SplitHostPort(target); err == nil {
+			return target
+		}
+	}
+	_, port, _ := net.SplitHostPort(addr)
+	return net.JoinHostPort(strings.Trim(target, "[]"), port)
+}
+
+// ------------------------------------------------------------------------------ dnsCache
+// DNS 缓存，在 ttl 时间内复用域名解析的结果。刷新失败时继续使用过期的结果
+type dnsCache struct {
+	ttl        time.Duration
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	entries    map[string]*dnsEntry
+	lock       sync.Mutex
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:        ttl,
+		lookupHost: net.DefaultResolver.LookupHost,
+		entries:    make(map[string]*dnsEntry),
+	}
+}
+
+func (this *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	this.lock.Lock()
+	entry := this.entries[host]
+	this.lock.Unlock()
+	if entry != nil && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := this.lookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		if entry != nil {
+			return entry.addrs, nil
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+	this.lock.Lock()
+	this.entries[host] = &dnsEntry{addrs: addrs, expires: time.Now().Add(this.ttl)}
+	this.lock.Unlock()
+	return addrs, nil
+}
@@ -0,0 +1,123 @@
+package httpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProfile_Resolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	if err := RegisterProfile("test-resolve", &ClientConfig{Resolve: map[string]string{
+		"api.example.com":     "127.0.0.1",
+		"api2.example.com:80": server.Listener.Addr().String(),
+	}}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	for _, url := range []string{"http://api.example.com:" + port, "http://API2.example.com"} {
+		client := Open("test-resolve").SetUrl(url).Get()
+		if err := client.Error(); err != nil {
+			t.Errorf("error occured: %v", err)
+		} else if host := client.ResponseText(); !strings.EqualFold(host, strings.TrimPrefix(url, "http://")) {
+			t.Errorf("assert faild: %v", host)
+		}
+		client.Close()
+	}
+
+	// 通过 DNS 缓存解析
+	if err := RegisterProfile("test-dns-cache", &ClientConfig{DNSCacheTTL: time.Minute}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	client := Open("test-dns-cache").SetUrl("http://localhost:" + port).Get()
+	defer client.Close()
+	if err := client.Error(); err != nil || client.ResponseText() != "localhost:"+port {
+		t.Errorf("assert faild, err=%v, text=%v", err, client.ResponseText())
+	}
+
+	if err := RegisterProfile("test-resolve", &ClientConfig{Resolve: map[string]string{"api.example.com": ""}}); err == nil {
+		t.Errorf("empty address should not be allowed")
+	}
+}
+
+func TestProfile_UnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpClient")
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "test.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix socket not supported: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	configs := map[string]*ClientConfig{
+		"http://docker/v1/info": {UnixSocket: "unix://" + sock},
+		"http://local/v2/info":  {Resolve: map[string]string{"local": "unix://" + sock}},
+	}
+	for url, cfg := range configs {
+		if err := RegisterProfile("test-unix", cfg); err != nil {
+			t.Errorf("error occured: %v", err)
+			return
+		}
+		client := Open("test-unix").SetUrl(url).Get()
+		if err := client.Error(); err != nil {
+			t.Errorf("error occured: %v", err)
+		} else if text := client.ResponseText(); "http://"+text != url {
+			t.Errorf("assert faild: %v", text)
+		}
+		client.Close()
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	var count int32
+	cache := newDNSCache(50 * time.Millisecond)
+	cache.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if atomic.AddInt32(&count, 1) > 2 {
+			return nil, &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if addrs, err := cache.lookup(context.Background(), "example.com"); err != nil || len(addrs) != 1 {
+			t.Errorf("assert faild, err=%v, addrs=%v", err, addrs)
+		}
+	}
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Errorf("should lookup once, got %v", n)
+	}
+
+	// 过期之后重新解析，解析失败时使用过期的结果
+	time.Sleep(60 * time.Millisecond)
+	cache.lookup(context.Background(), "example.com")
+	time.Sleep(60 * time.Millisecond)
+	if addrs, err := cache.lookup(context.Background(), "example.com"); err != nil || len(addrs) != 1 || atomic.LoadInt32(&count) != 3 {
+		t.Errorf("assert faild, err=%v, addrs=%v, count=%v", err, addrs, count)
+	}
+	if _, err := cache.lookup(context.Background(), "other.com"); err == nil {
+		t.Errorf("lookup should fail")
+	}
+}
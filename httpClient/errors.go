@@ -0,0 +1,132 @@
+package httpClient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// HTTPError、DecodeError 中保存的 Response Body 的最大长度，超过的部分会被截断
+var MaxErrorBodySize = 1024
+
+// 服务端返回了非 2xx、3xx 的状态码
+type HTTPError struct {
+	Method     string        // 请求的 Method
+	URL        string        // 请求的 Url
+	StatusCode int           // 状态码
+	Status     string        // 状态，如 "404 Not Found"
+	Body       string        // Response Body（超过 MaxErrorBodySize 时被截断）
+	Attempts   int           // 请求次数（包含重试）
+	Elapsed    time.Duration // 总耗时（包含重试）
+}
+
+func (this *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: %s", this.Method, this.URL, this.Status)
+}
+
+// 请求超时
+type TimeoutError struct {
+	Method   string        // 请求的 Method
+	URL      string        // 请求的 Url
+	Attempts int           // 请求次数（包含重试）
+	Elapsed  time.Duration // 总耗时（包含重试）
+	Err      error         // 原始错误
+}
+
+func (this *TimeoutError) Error() string {
+	return fmt.Sprintf("%s %s: timeout after %v attempts (%v): %v", this.Method, this.URL, this.Attempts, this.Elapsed, unwrapUrlError(this.Err))
+}
+
+func (this *TimeoutError) Unwrap() error { return this.Err }
+
+// 实现 net.Error 接口
+func (this *TimeoutError) Timeout() bool { return true }
+
+// 实现 net.Error 接口
+func (this *TimeoutError) Temporary() bool { return true }
+
+// 请求没有得到服务端的响应（如无法建立连接、连接被重置等）
+type TransportError struct {
+	Method   string        // 请求的 Method
+	URL      string        // 请求的 Url
+	Attempts int           // 请求次数（包含重试）
+	Elapsed  time.Duration // 总耗时（包含重试）
+	Err      error         // 原始错误
+}
+
+func (this *TransportError) Error() string {
+	return fmt.Sprintf("%s %s: %v", this.Method, this.URL, unwrapUrlError(this.Err))
+}
+
+func (this *TransportError) Unwrap() error { return this.Err }
+
+// 反序列化 Response Body 失败
+type DecodeError struct {
+	ContentType string // Response 的 Content-Type
+	Body        string // Response Body（超过 MaxErrorBodySize 时被截断）
+	Err         error  // 原始错误
+}
+
+func (this *DecodeError) Error() string {
+	return fmt.Sprintf("decode error, contentType: %s, responseText: %s, err=%v", this.ContentType, this.Body, this.Err)
+}
+
+func (this *DecodeError) Unwrap() error { return this.Err }
+
+func newDecodeError(contentType string, data []byte, err error) *DecodeError {
+	return &DecodeError{ContentType: contentType, Body: truncateBody(data), Err: err}
+}
+
+// 将请求过程中发生的错误转换为对应的类型，并补充请求次数、耗时等信息
+func (this *Client) wrapError(err error, elapsed time.Duration) error {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		httpErr.Attempts, httpErr.Elapsed = this.attempts, elapsed
+		return err
+	}
+
+	// 只转换发送请求过程中产生的错误，拦截器等返回的错误保持原样
+	var urlErr *url.Error
+	var netErr net.Error
+	if !errors.As(err, &urlErr) && !errors.As(err, &netErr) {
+		return err
+	}
+	var timeoutErr *TimeoutError
+	var transportErr *TransportError
+	if errors.As(err, &timeoutErr) || errors.As(err, &transportErr) {
+		return err
+	}
+
+	if IsTimeoutError(err) {
+		return &TimeoutError{Method: this.method, URL: this.RequestUrl(), Attempts: this.attempts, Elapsed: elapsed, Err: err}
+	}
+	return &TransportError{Method: this.method, URL: this.RequestUrl(), Attempts: this.attempts, Elapsed: elapsed, Err: err}
+}
+
+func (this *Client) newHTTPError() *HTTPError {
+	return &HTTPError{
+		Method:     this.method,
+		URL:        this.RequestUrl(),
+		StatusCode: this.StatusCode,
+		Status:     this.Status,
+		Body:       truncateBody(this.responseData),
+		Attempts:   this.attempts,
+	}
+}
+
+func truncateBody(data []byte) string {
+	if MaxErrorBodySize > 0 && len(data) > MaxErrorBodySize {
+		return string(data[:MaxErrorBodySize]) + "..."
+	}
+	return string(data)
+}
+
+// url.Error 的错误信息中已经包含了 Method 和 Url，取出其内部的错误以免重复
+func unwrapUrlError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package httpClient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+		w.Write([]byte(strings.Repeat("x", 2000)))
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL+"/api").SetRetry(2, time.Millisecond).Get()
+	defer client.Close()
+	var httpErr *HTTPError
+	if !errors.As(client.Error(), &httpErr) {
+		t.Errorf("expect *HTTPError, got %v", client.Error())
+		return
+	}
+	if httpErr.Method != "GET" || httpErr.URL != server.URL+"/api" || httpErr.StatusCode != 503 || httpErr.Attempts != 2 || httpErr.Elapsed <= 0 {
+		t.Errorf("assert faild: %+v", httpErr)
+	}
+	if len(httpErr.Body) != MaxErrorBodySize+3 {
+		t.Errorf("body should be truncated, got %v bytes", len(httpErr.Body))
+	}
+
+	if _, err := client.Success(); !errors.As(err, &httpErr) {
+		t.Errorf("expect *HTTPError, got %v", err)
+	}
+}
+
+func TestClient_TransportError(t *testing.T) {
+	client := Open().SetUrl("http://127.0.0.1:1/never").SetRetryPolicy(NoRetry).Get()
+	defer client.Close()
+	var transportErr *TransportError
+	if !errors.As(client.Error(), &transportErr) {
+		t.Errorf("expect *TransportError, got %v", client.Error())
+	} else if transportErr.Attempts != 1 || transportErr.Method != "GET" {
+		t.Errorf("assert faild: %+v", transportErr)
+	}
+}
+
+func TestClient_TimeoutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := (&Client{Client: &http.Client{}}).Reborn()
+	client.Timeout = 50 * time.Millisecond
+	client.SetUrl(server.URL).Get()
+	var timeoutErr *TimeoutError
+	if !errors.As(client.Error(), &timeoutErr) {
+		t.Errorf("expect *TimeoutError, got %v", client.Error())
+	} else if !IsTimeoutError(timeoutErr) || IsRetryableError(timeoutErr) {
+		t.Errorf("assert faild: %v", timeoutErr)
+	}
+}
+
+func TestClient_DecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	var obj map[string]interface{}
+	client := Open().SetUrl(server.URL).GetJson(&obj)
+	defer client.Close()
+	var decodeErr *DecodeError
+	if !errors.As(client.Error(), &decodeErr) {
+		t.Errorf("expect *DecodeError, got %v", client.Error())
+	} else if decodeErr.Body != "not json" || decodeErr.ContentType != "application/json" || decodeErr.Err == nil {
+		t.Errorf("assert faild: %+v", decodeErr)
+	}
+}
@@ -10,7 +10,8 @@ import (
 // ------------------------------------------------------------------------------ Request
 var requestHandler []func(*Client, error, float32)
 
-// 当 HTTP 请求执行完毕之后触发
+// 当 HTTP 请求执行完毕之后触发（包含重试在内只触发一次）。
+// 如果需要修改请求，或者观察每一次重试，请使用拦截器（AddInterceptor）。
 func OnRequest(f func(c *Client, err error, took float32)) {
 	if f != nil {
 		requestHandler = append(requestHandler, f)
@@ -28,3 +29,53 @@ func fireRequest(c *Client, err error, took float32) {
 		f(c, err, took)
 	}
 }
+
+// ------------------------------------------------------------------------------ CircuitBreaker
+var circuitStateHandler []func(string, CircuitState, CircuitState)
+
+// 当熔断器的状态发生变化时触发
+//   host: 发生变化的 Host，如 http://example.com
+//   from: 变化之前的状态
+//   to: 变化之后的状态
+func OnCircuitStateChange(f func(host string, from, to CircuitState)) {
+	if f != nil {
+		circuitStateHandler = append(circuitStateHandler, f)
+	}
+}
+
+func fireCircuitStateChange(host string, from, to CircuitState) {
+	defer func() {
+		if e := recover(); e != nil {
+			os.Stderr.WriteString(fmt.Sprintf("[%v] httpClient panic: %v\n", time.Now().Format("2006-01-02 15:04:05.000"), e))
+			debug.PrintStack()
+		}
+	}()
+	for _, f := range circuitStateHandler {
+		f(host, from, to)
+	}
+}
+
+// ------------------------------------------------------------------------------ RateLimiter
+var rateLimitHandler []func(*Client, string, time.Duration, error)
+
+// 当请求因为限流而等待，或者被限流器拒绝时触发（每次重试都会触发）
+//   key: 限流的 Key（Host 或者 profile 名称）
+//   wait: 等待的时间
+//   err: 被拒绝时为 *RateLimitError，等待过程中 context 结束时为 context 的错误，否则为 nil
+func OnRateLimit(f func(c *Client, key string, wait time.Duration, err error)) {
+	if f != nil {
+		rateLimitHandler = append(rateLimitHandler, f)
+	}
+}
+
+func fireRateLimit(c *Client, key string, wait time.Duration, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			os.Stderr.WriteString(fmt.Sprintf("[%v] httpClient panic: %v\n", time.Now().Format("2006-01-02 15:04:05.000"), e))
+			debug.PrintStack()
+		}
+	}()
+	for _, f := range rateLimitHandler {
+		f(c, key, wait, err)
+	}
+}
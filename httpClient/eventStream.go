@@ -0,0 +1,225 @@
+package httpClient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"yelo/go-util/jsonUtil"
+)
+
+// 事件流的格式
+type StreamFormat int
+
+const (
+	StreamAuto   StreamFormat = 0 // 根据 Response 的 Content-Type 判断：text/event-stream 按照 SSE 解析，否则按照 NDJSON 解析
+	StreamSSE    StreamFormat = 1 // Server-Sent Events
+	StreamNDJSON StreamFormat = 2 // 每行一个 Json（newline-delimited JSON）
+)
+
+// 事件流中的一个事件
+type Event struct {
+	Id    string        // 事件 ID（SSE 的 id 字段，NDJSON 为空）
+	Event string        // 事件类型（SSE 的 event 字段，默认为 message；NDJSON 为空）
+	Data  string        // 事件内容（SSE 中多个 data 字段以 \n 连接；NDJSON 为一行的内容）
+	Retry time.Duration // 服务端建议的重连间隔（SSE 的 retry 字段），0 表示没有设置
+}
+
+// 将事件内容按照 Json 格式反序列化到参数指定的对象中
+func (this *Event) Decode(obj interface{}) error {
+	return jsonUtil.Unmarshal([]byte(this.Data), obj)
+}
+
+type EventStreamOptions struct {
+	Format         StreamFormat  // 事件流的格式，默认根据 Content-Type 判断
+	LastEventId    string        // 第一次连接时发送的 Last-Event-ID，用于从指定的事件之后继续接收
+	MaxReconnects  int           // 连接断开之后连续重连的最大次数（收到事件之后重新计数），0 表示不限制，<0 表示不重连
+	ReconnectDelay time.Duration // 重连的间隔，默认 3 秒，服务端通过 SSE 的 retry 字段指定时以服务端为准
+}
+
+// （使用已经设置好的 Method、Url、Body、Header 等）以流式方式发起请求，并将 Response Body 按照 SSE 或者 NDJSON 格式解析为事件，依次交给 handler 处理。
+// 连接断开之后自动重连，并通过 Last-Event-ID 告知服务端最后收到的事件 ID（服务端返回 204 时不再重连）。每次连接失败时按照重试策略重试。
+// 函数在以下情况下返回：
+//   handler 返回错误：返回该错误
+//   context 结束：返回 context 结束的原因
+//   服务端返回错误状态码：返回对应的 *HTTPError
+//   超过了最大重连次数（连接失败与连接断开一样会重连）：返回最后一次的错误（连接正常结束时返回 nil）
+// 注意：http.Client 的 Timeout 同样作用于读取事件流的过程，请通过 SetTimeout(0) 取消超时并使用 context 控制。在函数返回之前请不要调用 Close。
+func (this *Client) EventStream(ctx context.Context, opt *EventStreamOptions, handler func(e *Event) error) error {
+	o := EventStreamOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	if o.ReconnectDelay <= 0 {
+		o.ReconnectDelay = 3 * time.Second
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	switch o.Format {
+	case StreamSSE:
+		if getHeader(this.headers, "Accept") == "" {
+			this.SetHeader("Accept", "text/event-stream")
+		}
+	case StreamNDJSON:
+		if getHeader(this.headers, "Accept") == "" {
+			this.SetHeader("Accept", "application/x-ndjson")
+		}
+	}
+
+	reader := &eventReader{lastId: o.LastEventId, delay: o.ReconnectDelay}
+	for failures := 0; ; failures++ {
+		if reader.lastId != "" {
+			this.SetHeader("Last-Event-ID", reader.lastId)
+		}
+		var received bool
+		var readErr error
+		body, err := this.SetCtx(ctx).RequestStream()
+		if err != nil {
+			// 服务端返回错误状态码时不再重连；连接失败（如无法建立连接）时与连接断开相同，等待之后重连
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				return err
+			}
+			readErr = err
+		} else if this.StatusCode == 204 {
+			body.Close()
+			return nil
+		} else {
+			format := o.Format
+			if format == StreamAuto {
+				if strings.Contains(strings.ToLower(this.responseHeader.Get("Content-Type")), "text/event-stream") {
+					format = StreamSSE
+				} else {
+					format = StreamNDJSON
+				}
+			}
+			var handlerErr error
+			received, handlerErr, readErr = reader.read(body, format, handler)
+			body.Close()
+			if handlerErr != nil {
+				return handlerErr
+			}
+		}
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if received {
+			failures = 0
+		}
+		if o.MaxReconnects < 0 || (o.MaxReconnects > 0 && failures >= o.MaxReconnects) {
+			return readErr
+		}
+		if !sleepCtx(ctx, reader.delay) {
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// 与 EventStream 相同，但是通过 channel 返回事件。
+// 事件流结束之后 events 被关闭，之后 errs 中会收到 EventStream 的返回值（正常结束时为 nil）。参数 buffer 为 events 的缓冲区大小。
+func (this *Client) EventStreamChan(ctx context.Context, opt *EventStreamOptions, buffer int) (<-chan *Event, <-chan error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if buffer < 0 {
+		buffer = 0
+	}
+	events, errs := make(chan *Event, buffer), make(chan error, 1)
+	go func() {
+		err := this.EventStream(ctx, opt, func(e *Event) error {
+			select {
+			case events <- e:
+				return nil
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		})
+		close(events)
+		errs <- err
+		close(errs)
+	}()
+	return events, errs
+}
+
+// 事件流的解析器，在多次重连之间保存最后的事件 ID 和重连间隔
+type eventReader struct {
+	lastId string
+	delay  time.Duration
+}
+
+// 读取事件直到 body 结束，返回是否收到过事件、handler 返回的错误以及读取的错误（正常结束时为 nil）
+func (this *eventReader) read(body io.Reader, format StreamFormat, handler func(e *Event) error) (bool, error, error) {
+	received := false
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+	var eventType string
+	var retry time.Duration
+	hasData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				err = nil
+			}
+			return received, nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if format == StreamNDJSON {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			received = true
+			if err := handler(&Event{Data: line}); err != nil {
+				return received, err, nil
+			}
+			continue
+		}
+
+		// SSE：空行表示一个事件结束
+		if line == "" {
+			if hasData {
+				received = true
+				e := &Event{Id: this.lastId, Event: eventType, Data: strings.TrimSuffix(data.String(), "\n"), Retry: retry}
+				if e.Event == "" {
+					e.Event = "message"
+				}
+				if err := handler(e); err != nil {
+					return received, err, nil
+				}
+			}
+			data.Reset()
+			eventType, retry, hasData = "", 0, false
+			continue
+		}
+		if line[0] == ':' {
+			// 注释（通常用于保持连接）
+			continue
+		}
+		field, value := line, ""
+		if pos := strings.Index(line, ":"); pos != -1 {
+			field, value = line[:pos], strings.TrimPrefix(line[pos+1:], " ")
+		}
+		switch field {
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "event":
+			eventType = value
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				this.lastId = value
+			}
+		case "retry":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+				retry = time.Duration(n) * time.Millisecond
+				this.delay = retry
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_EventStreamSSE(t *testing.T) {
+	var connects int32
+	var lastIds []string
+	var lock sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&connects, 1)
+		lock.Lock()
+		lastIds = append(lastIds, r.Header.Get("Last-Event-ID"))
+		lock.Unlock()
+		if n > 2 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		start, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+		fmt.Fprintf(w, ": keep-alive\nretry: 10\n\n")
+		for i := start + 1; i <= start+2; i++ {
+			fmt.Fprintf(w, "id: %d\nevent: tick\ndata: {\"n\":%d}\r\ndata: line2\n\n", i, i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL)
+	defer client.Close()
+	var events []*Event
+	err := client.EventStream(context.Background(), &EventStreamOptions{ReconnectDelay: time.Minute}, func(e *Event) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(events) != 4 || atomic.LoadInt32(&connects) != 3 || fmt.Sprint(lastIds) != "[ 2 4]" {
+		t.Errorf("assert faild, events=%v, connects=%v, lastIds=%v", len(events), connects, lastIds)
+		return
+	}
+	for i, e := range events {
+		if e.Id != strconv.Itoa(i+1) || e.Event != "tick" || e.Data != fmt.Sprintf("{\"n\":%d}\nline2", i+1) || e.Retry != 0 {
+			t.Errorf("assert faild: %+v", e)
+		}
+	}
+}
+
+func TestClient_EventStreamNDJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "{\"n\":%d}\n\n", i)
+		}
+		fmt.Fprintf(w, `{"n":4}`)
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL)
+	defer client.Close()
+	sum := 0
+	err := client.EventStream(nil, &EventStreamOptions{MaxReconnects: -1}, func(e *Event) error {
+		var obj struct{ N int }
+		if err := e.Decode(&obj); err != nil {
+			return err
+		}
+		sum += obj.N
+		return nil
+	})
+	if err != nil || sum != 10 {
+		t.Errorf("assert faild, err=%v, sum=%v", err, sum)
+	}
+}
+
+func TestClient_EventStreamReconnectError(t *testing.T) {
+	var connects int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不复用连接，避免 Transport 在复用的连接断开时自动重试
+		w.Header().Set("Connection", "close")
+		switch atomic.AddInt32(&connects, 1) {
+		case 1:
+			fmt.Fprintf(w, "{\"n\":1}\n")
+		case 2, 3:
+			// 连接失败
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case 4:
+			fmt.Fprintf(w, "{\"n\":2}\n")
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL).SetRetryPolicy(NoRetry)
+	defer client.Close()
+	var events int
+	err := client.EventStream(nil, &EventStreamOptions{Format: StreamNDJSON, MaxReconnects: 3, ReconnectDelay: 10 * time.Millisecond}, func(e *Event) error {
+		events++
+		return nil
+	})
+	// 连接失败时重连，服务端返回错误状态码时返回
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 404 || events != 2 || atomic.LoadInt32(&connects) != 5 {
+		t.Errorf("assert faild, err=%v, events=%v, connects=%v", err, events, connects)
+	}
+
+	// 超过最大重连次数时返回最后一次的错误
+	atomic.StoreInt32(&connects, 1)
+	err = client.EventStream(nil, &EventStreamOptions{Format: StreamNDJSON, MaxReconnects: 1, ReconnectDelay: 10 * time.Millisecond}, func(e *Event) error { return nil })
+	if err == nil || atomic.LoadInt32(&connects) != 3 {
+		t.Errorf("assert faild, err=%v, connects=%v", err, connects)
+	}
+}
+
+func TestClient_EventStreamCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL)
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, errs := client.EventStreamChan(ctx, nil, 0)
+	count := 0
+	for e := range events {
+		if e.Data != strconv.Itoa(count) {
+			t.Errorf("assert faild: %v", e.Data)
+		}
+		if count++; count == 3 {
+			cancel()
+		}
+	}
+	if err := <-errs; err != context.Canceled || count < 3 {
+		t.Errorf("assert faild, err=%v, count=%v", err, count)
+	}
+}
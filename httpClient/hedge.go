@@ -0,0 +1,127 @@
+package httpClient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 设置对冲请求：（每次重试）请求发出之后 delay 时间内没有得到响应时，再发送一个相同的请求，使用先得到的响应，并取消另一个请求。
+// 用于降低长尾延迟，delay 一般设置为接口延迟的 P95 左右。<=0 表示不使用对冲请求。
+// 只对幂等请求（参考 BackoffRetry.RetryNonIdempotent）以及可以重复读取 Request Body 的请求生效。
+// 等同于 AddInterceptor(HedgeInterceptor(delay))
+func (this *Client) SetHedge(delay time.Duration) *Client {
+	if delay > 0 {
+		this.AddInterceptor(HedgeInterceptor(delay))
+	}
+	return this
+}
+
+// 获取对冲请求的拦截器，参考 Client.SetHedge
+func HedgeInterceptor(delay time.Duration) Interceptor {
+	return func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		if delay <= 0 || !isIdempotent(req.Method, c.headers) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return next(req)
+		}
+
+		type result struct {
+			index int
+			resp  *http.Response
+			err   error
+		}
+		// 两个请求会在不同的协程中经过后续的拦截器（可能会修改 Header 等），所以在启动协程之前分别复制一份请求，互不共享。
+		// 第一个请求使用原始的 Request Body，对冲请求的 Body 在发出时通过 GetBody 重新创建
+		var cancels []context.CancelFunc
+		newRequest := func() *http.Request {
+			ctx, cancel := context.WithCancel(req.Context())
+			cancels = append(cancels, cancel)
+			return req.Clone(ctx)
+		}
+		primary, hedge := newRequest(), newRequest()
+		results := make(chan *result, 2)
+		send := func(index int, r *http.Request) {
+			go func() {
+				resp, err := next(r)
+				results <- &result{index: index, resp: resp, err: err}
+			}()
+		}
+		defer func() {
+			if hedge != nil {
+				cancels[1]()
+			}
+		}()
+
+		send(0, primary)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		var firstErr error
+		for pending := 1; ; {
+			select {
+			case <-timer.C:
+				if req.Body != nil && req.Body != http.NoBody {
+					body, err := req.GetBody()
+					if err != nil {
+						continue
+					}
+					hedge.Body = body
+				}
+				send(1, hedge)
+				hedge = nil
+				pending++
+			case r := <-results:
+				pending--
+				if r.err == nil {
+					// 取消其他请求，并释放其结果
+					for i, cancel := range cancels {
+						if i != r.index {
+							cancel()
+						}
+					}
+					go func(n int) {
+						for ; n > 0; n-- {
+							if other := <-results; other.resp != nil {
+								other.resp.Body.Close()
+							}
+						}
+					}(pending)
+					r.resp.Body = &cancelReadCloser{ReadCloser: r.resp.Body, cancel: cancels[r.index]}
+					return r.resp, nil
+				}
+				cancels[r.index]()
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				if pending == 0 {
+					// 对冲请求尚未发出时第一个请求就失败了，直接返回错误（由重试策略决定是否重试）
+					return nil, firstErr
+				}
+			}
+		}
+	}
+}
+
+// 复制请求用于对冲，Request Body 通过 GetBody 重新创建
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// 关闭时同时取消对应请求的 context
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (this *cancelReadCloser) Close() error {
+	err := this.ReadCloser.Close()
+	this.cancel()
+	return err
+}
@@ -0,0 +1,92 @@
+package httpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Hedge(t *testing.T) {
+	var count, canceled int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&count, 1) == 1 {
+			// 第一个请求很慢，应该被对冲请求取代并取消
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+				atomic.AddInt32(&canceled, 1)
+				return
+			}
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	begin := time.Now()
+	client := Open().SetUrl(server.URL).SetMethod("PUT").SetBody("hello").SetHedge(50 * time.Millisecond).Request()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if client.ResponseText() != "hello" {
+		t.Errorf("assert faild: %v", client.ResponseText())
+	}
+	if took := time.Since(begin); took > time.Second {
+		t.Errorf("hedged request should win, took %v", took)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&count) != 2 || atomic.LoadInt32(&canceled) != 1 {
+		t.Errorf("assert faild, count=%v, canceled=%v", count, canceled)
+	}
+}
+
+func TestClient_HedgeSkipped(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// 非幂等请求不对冲
+	client := Open().SetUrl(server.URL).SetBody("abc").SetHedge(10 * time.Millisecond).Post()
+	defer client.Close()
+	// 足够快的请求不对冲
+	client.Reborn().SetUrl(server.URL).SetHedge(time.Second).Get()
+	if n := atomic.LoadInt32(&count); n != 2 {
+		t.Errorf("request should not be hedged, got %v requests", n)
+	}
+}
+
+func TestClient_HedgeConcurrentInterceptors(t *testing.T) {
+	var count, unauthorized int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			atomic.AddInt32(&unauthorized, 1)
+		}
+		if atomic.AddInt32(&count, 1)%2 == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	// 对冲请求之后的认证、限流拦截器会在两个协程中并发执行，使用 go test -race 检查
+	limiter := NewRateLimiter(&RateLimitOptions{Rate: 10000, Burst: 1})
+	for i := 0; i < 20; i++ {
+		client := Open().SetUrl(server.URL).SetMethod("PUT").SetBody("hello").
+			SetHedge(time.Millisecond).SetAuth(&BasicAuth{Username: "user", Password: "pass"}).SetRateLimiter(limiter).Request()
+		if err := client.Error(); err != nil || client.ResponseText() != "hello" {
+			t.Errorf("assert faild, err=%v, text=%v", err, client.ResponseText())
+		}
+		client.RateLimitWait()
+		client.Close()
+	}
+	if n := atomic.LoadInt32(&unauthorized); n != 0 {
+		t.Errorf("%v requests without auth", n)
+	}
+}
@@ -0,0 +1,89 @@
+package httpClient
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 发送一次 HTTP 请求的函数
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// 拦截器。每次发送请求（包括每一次重试）时，按照注册顺序依次调用，拦截器可以：
+//   1、修改 req 的 Header、URL 等（如签名、追踪 Header），然后调用 next(req) 继续发送请求
+//   2、不调用 next，直接返回构造好的 *http.Response 或者 error（短路）
+//   3、观察 next 返回的结果，可以通过 c.Attempts() 获取当前是第几次请求
+// 拦截器返回的 *http.Response 的 Body 会由 httpClient 负责关闭。
+type Interceptor func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error)
+
+var globalInterceptors []Interceptor
+
+// 注册全局拦截器，对所有的 Client 生效。请在程序初始化阶段调用。
+// 全局拦截器先于通过 Client.AddInterceptor 注册的拦截器执行。
+func AddInterceptor(f ...Interceptor) {
+	for _, v := range f {
+		if v != nil {
+			globalInterceptors = append(globalInterceptors, v)
+		}
+	}
+}
+
+// 注册只对当前 Client 生效的拦截器。调用 Reborn 或者 Close 之后失效。
+func (this *Client) AddInterceptor(f ...Interceptor) *Client {
+	for _, v := range f {
+		if v != nil {
+			this.interceptors = append(this.interceptors, v)
+		}
+	}
+	return this
+}
+
+// 依次经过全局拦截器、当前 Client 的拦截器之后发送请求
+func (this *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	chain := make([]Interceptor, 0, len(globalInterceptors)+len(this.interceptors))
+	chain = append(chain, globalInterceptors...)
+	chain = append(chain, this.interceptors...)
+	if len(chain) == 0 {
+		return this.Do(req)
+	}
+
+	var next func(i int) RoundTripFunc
+	next = func(i int) RoundTripFunc {
+		if i == len(chain) {
+			return this.Do
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := chain[i](this, req, next(i+1))
+			if err == nil && resp == nil {
+				// 在每一层转换为错误，确保调用 next 的拦截器总是能拿到 Response 或者 error
+				return nil, fmt.Errorf("拦截器没有返回 Response")
+			}
+			return resp, err
+		}
+	}
+
+	resp, err := next(0)(req)
+	// 拦截器短路（没有调用 next）时 Request Body 不会被 Transport 关闭，在此关闭以释放资源（如 multipart 的后台协程）
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	// 拦截器构造的 Response 可能缺少部分字段，补全之
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	if resp.Status == "" {
+		resp.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	return resp, nil
+}
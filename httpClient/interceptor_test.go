@@ -0,0 +1,96 @@
+package httpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Interceptor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/api" {
+			w.WriteHeader(404)
+			return
+		}
+		if r.Header.Get("X-Trace-Id") == "" {
+			w.WriteHeader(500)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Trace-Id") + "|" + r.Header.Get("X-Global")))
+	}))
+	defer server.Close()
+
+	AddInterceptor(func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		req.Header.Set("X-Global", "g")
+		return next(req)
+	})
+	defer func() { globalInterceptors = nil }()
+
+	var attempts []int
+	client := Open().SetUrl(server.URL+"/v1/api").SetRetry(3, time.Millisecond).AddInterceptor(
+		func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+			attempts = append(attempts, c.Attempts())
+			resp, err := next(req)
+			if err == nil && resp.StatusCode == 500 {
+				// 第一次请求没有设置 X-Trace-Id，返回 500，重试时设置
+				c.SetHeader("X-Trace-Id", "abc")
+			}
+			return resp, err
+		},
+		func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+			req.URL.Path = strings.Replace(req.URL.Path, "/v1/", "/v2/", 1)
+			return next(req)
+		},
+	).Get()
+	defer client.Close()
+
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if text := client.ResponseText(); text != "abc|g" {
+		t.Errorf("assert faild: %v", text)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("each attempt should be observed: %v", attempts)
+	}
+}
+
+func TestClient_InterceptorShortCircuit(t *testing.T) {
+	client := Open().SetUrl("http://127.0.0.1:1/never").AddInterceptor(func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"cached":true}`))}, nil
+	})
+	defer client.Close()
+
+	var obj struct {
+		Cached bool `json:"cached"`
+	}
+	if err := client.GetJson(&obj).Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if !obj.Cached || client.Status != "200 OK" {
+		t.Errorf("assert faild: %+v, %v", obj, client.Status)
+	}
+
+	// Reborn 之后拦截器失效
+	if client.Reborn().SetUrl("http://127.0.0.1:1/never").SetRetryPolicy(NoRetry).Get().Error() == nil {
+		t.Errorf("interceptor should be cleared after Reborn")
+	}
+}
+
+func TestClient_InterceptorNilResponse(t *testing.T) {
+	// 拦截器返回 (nil, nil) 时，位于它之前的拦截器（认证、缓存、录制）拿到的是错误，而不是 nil 的 Response
+	dir, _ := ioutil.TempDir("", "httpClient")
+	defer os.RemoveAll(dir)
+	recorder, _ := NewCassette(filepath.Join(dir, "api.json"), &CassetteOptions{Mode: CassetteRecord})
+	auth := NewBearerAuth("token", func() (string, error) { return "token2", nil })
+	client := Open().SetUrl("http://127.0.0.1:1/never").SetRetryPolicy(NoRetry).SetAuth(auth).SetCache(NewResponseCache(nil)).UseCassette(recorder).AddInterceptor(func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		return nil, nil
+	})
+	defer client.Close()
+	if err := client.Get().Error(); err == nil || !strings.Contains(err.Error(), "拦截器没有返回 Response") {
+		t.Errorf("assert faild: %v", err)
+	}
+}
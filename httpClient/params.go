@@ -0,0 +1,223 @@
+package httpClient
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"yelo/go-util/convertor"
+)
+
+// 设置 Query 参数，会覆盖 Url 中已经存在的同名参数。val 为 slice 或者 array 时设置为多个值。
+func (this *Client) SetQuery(key string, val interface{}) *Client {
+	if this.query == nil {
+		this.query = make(url.Values)
+	}
+	this.query[key] = toQueryValues(val)
+	this.setQueryReplace(key)
+	return this
+}
+
+func (this *Client) setQueryReplace(key string) {
+	if this.queryReplace == nil {
+		this.queryReplace = make(map[string]bool)
+	}
+	this.queryReplace[key] = true
+}
+
+// 追加 Query 参数，不会覆盖已经存在的同名参数（包括 Url 中已经存在的参数）。
+func (this *Client) AddQuery(key string, val interface{}) *Client {
+	if this.query == nil {
+		this.query = make(url.Values)
+	}
+	this.query[key] = append(this.query[key], toQueryValues(val)...)
+	return this
+}
+
+// 批量设置 Query 参数，参考 SetQuery
+func (this *Client) SetQueryMulti(dict map[string]interface{}) *Client {
+	for key, val := range dict {
+		this.SetQuery(key, val)
+	}
+	return this
+}
+
+// 将结构体的字段设置为 Query 参数，会覆盖 Url 中已经存在的同名参数。参考 StructToValues
+func (this *Client) SetQueryStruct(obj interface{}) *Client {
+	for key, vals := range StructToValues(obj) {
+		if this.query == nil {
+			this.query = make(url.Values)
+		}
+		this.query[key] = vals
+		this.setQueryReplace(key)
+	}
+	return this
+}
+
+// 获取已经设置的 Query 参数（不包括 Url 中已经存在的参数）
+func (this *Client) Query() url.Values {
+	return this.query
+}
+
+// 设置 Url 模板中的路径参数。如 Url 为 /users/{id}/orders，则 SetPathParam("id", 123) 之后请求的 Url 为 /users/123/orders。
+// 参数值会按照路径的规则转义。
+func (this *Client) SetPathParam(key string, val interface{}) *Client {
+	if this.pathParams == nil {
+		this.pathParams = make(map[string]string)
+	}
+	this.pathParams[key] = convertor.ToStringNoError(val)
+	return this
+}
+
+// 批量设置 Url 模板中的路径参数，参考 SetPathParam
+func (this *Client) SetPathParams(dict map[string]interface{}) *Client {
+	for key, val := range dict {
+		this.SetPathParam(key, val)
+	}
+	return this
+}
+
+// 设置 application/x-www-form-urlencoded 格式的 Request Body。val 为 slice 或者 array 时设置为多个值。
+func (this *Client) SetForm(dict map[string]interface{}) *Client {
+	values := make(url.Values, len(dict))
+	for key, val := range dict {
+		values[key] = toQueryValues(val)
+	}
+	return this.SetBody(values)
+}
+
+// 设置 application/x-www-form-urlencoded 格式的 Request Body。等同于 SetBody(values)
+func (this *Client) SetFormValues(values url.Values) *Client {
+	return this.SetBody(values)
+}
+
+// 获取实际请求的 Url（填充路径参数、合并 Query 参数之后的 Url）
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) RequestUrl() string {
+	s := this.url
+	for key, val := range this.pathParams {
+		s = strings.Replace(s, "{"+key+"}", url.PathEscape(val), -1)
+	}
+	if len(this.query) == 0 {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	// Url 中原有的参数保持原样（顺序和编码都不变），只去掉需要被 SetQuery 覆盖的参数，新的参数追加在后面
+	arr := make([]string, 0)
+	if u.RawQuery != "" {
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			key := pair
+			if pos := strings.Index(key, "="); pos != -1 {
+				key = key[:pos]
+			}
+			if k, err := url.QueryUnescape(key); err == nil && this.queryReplace[k] {
+				continue
+			}
+			arr = append(arr, pair)
+		}
+	}
+	if encoded := this.query.Encode(); encoded != "" {
+		arr = append(arr, encoded)
+	}
+	u.RawQuery = strings.Join(arr, "&")
+	return u.String()
+}
+
+// 将结构体转换为 url.Values。
+// 字段名优先取 url 标签，其次取 json 标签，都没有时使用字段名。标签为 "-" 的字段忽略；标签中包含 omitempty 时忽略零值。
+// 匿名嵌入的结构体会被展开，slice 或者 array 字段会转换为多个值。obj 为 map 时按照 key-value 转换。
+func StructToValues(obj interface{}) url.Values {
+	values := make(url.Values)
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return values
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		structToValues(v, values)
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			values[convertor.ToStringNoError(key.Interface())] = toQueryValues(v.MapIndex(key).Interface())
+		}
+	}
+	return values
+}
+
+func structToValues(v reflect.Value, values url.Values) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fieldVal := t.Field(i), v.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// 未导出的字段
+			continue
+		}
+
+		tag := field.Tag.Get("url")
+		if tag == "" {
+			tag = field.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, omitEmpty := tag, false
+		if pos := strings.Index(tag, ","); pos != -1 {
+			name, omitEmpty = tag[:pos], strings.Contains(tag[pos:], "omitempty")
+		}
+
+		for fieldVal.Kind() == reflect.Ptr || fieldVal.Kind() == reflect.Interface {
+			if fieldVal.IsNil() {
+				break
+			}
+			fieldVal = fieldVal.Elem()
+		}
+		if field.Anonymous && name == "" && fieldVal.Kind() == reflect.Struct {
+			structToValues(fieldVal, values)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if (fieldVal.Kind() == reflect.Ptr || fieldVal.Kind() == reflect.Interface) && fieldVal.IsNil() {
+			if !omitEmpty {
+				values[name] = []string{""}
+			}
+			continue
+		}
+		if omitEmpty && convertor.IsEmpty(fieldVal.Interface()) {
+			continue
+		}
+		values[name] = toQueryValues(fieldVal.Interface())
+	}
+}
+
+// 将参数转换为 Query 参数的值，slice 或者 array（[]byte 除外）转换为多个值
+func toQueryValues(val interface{}) []string {
+	if val == nil {
+		return []string{""}
+	}
+	switch t := val.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return append([]string(nil), t...)
+	case []byte:
+		return []string{string(t)}
+	}
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		arr := make([]string, v.Len())
+		for i := range arr {
+			arr[i] = convertor.ToStringNoError(v.Index(i).Interface())
+		}
+		return arr
+	}
+	return []string{convertor.ToStringNoError(val)}
+}
@@ -0,0 +1,92 @@
+package httpClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_QueryAndPathParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL+"/users/{id}/orders?a=1&b=2").
+		SetPathParam("id", "a b/c").
+		SetQuery("b", "x&y").
+		AddQuery("c", []int{1, 2}).
+		Get()
+	defer client.Close()
+
+	expect := "/users/a%20b%2Fc/orders?a=1&b=x%26y&c=1&c=2"
+	if text := client.ResponseText(); text != expect {
+		t.Errorf("assert faild: %v", text)
+	}
+	if params := client.RequestParams(); params.Api != expect || params.Host != server.URL {
+		t.Errorf("RequestParams not match: %+v", params)
+	}
+}
+
+func TestClient_AddQuery(t *testing.T) {
+	client := Open().SetUrl("http://example.com/api?z=1&a=%41&b=x+y")
+	defer client.Close()
+	if s := client.RequestUrl(); s != "http://example.com/api?z=1&a=%41&b=x+y" {
+		t.Errorf("assert faild: %v", s)
+	}
+
+	// 追加的参数不会覆盖 Url 中的同名参数，Url 中原有的参数保持不变
+	client.AddQuery("z", 2).AddQuery("c", "3")
+	if s := client.RequestUrl(); s != "http://example.com/api?z=1&a=%41&b=x+y&c=3&z=2" {
+		t.Errorf("assert faild: %v", s)
+	}
+	client.SetQuery("a", "new").AddQuery("a", "more")
+	if s := client.RequestUrl(); s != "http://example.com/api?z=1&b=x+y&a=new&a=more&c=3&z=2" {
+		t.Errorf("assert faild: %v", s)
+	}
+}
+
+func TestClient_SetQueryStruct(t *testing.T) {
+	type Page struct {
+		Page int `url:"page"`
+		Size int `url:"size,omitempty"`
+	}
+	type Query struct {
+		Page
+		Name    string   `url:"name"`
+		Tags    []string `json:"tags"`
+		Ignore  string   `url:"-"`
+		Empty   string   `url:"empty,omitempty"`
+		Keyword *string  `url:"kw,omitempty"`
+		hidden  string
+	}
+	values := StructToValues(&Query{Page: Page{Page: 2}, Name: "张三", Tags: []string{"a", "b"}, Ignore: "x", hidden: "y"})
+	expect := url.Values{"page": {"2"}, "name": {"张三"}, "tags": {"a", "b"}}
+	if values.Encode() != expect.Encode() {
+		t.Errorf("assert faild: %v", values.Encode())
+	}
+
+	client := Open().SetUrl("http://example.com/api").SetQueryStruct(&Query{Name: "a"})
+	defer client.Close()
+	if s := client.RequestUrl(); s != "http://example.com/api?name=a&page=0" {
+		t.Errorf("assert faild: %v", s)
+	}
+}
+
+func TestClient_SetForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Write([]byte(r.Header.Get("Content-Type") + "|" + r.PostForm.Encode()))
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL).SetForm(map[string]interface{}{"name": "a b", "id": 12, "tag": []string{"x", "y"}}).Post()
+	defer client.Close()
+	if text := client.ResponseText(); text != "application/x-www-form-urlencoded|id=12&name=a+b&tag=x&tag=y" {
+		t.Errorf("assert faild: %v", text)
+	}
+	if body := client.RequestParams().Body; body != "id=12&name=a+b&tag=x&tag=y" {
+		t.Errorf("RequestParams not match: %v", body)
+	}
+}
@@ -0,0 +1,139 @@
+package httpClient
+
+import (
+	"github.com/emirpasic/gods/lists/singlylinkedlist"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOpen_CloseTwice(t *testing.T) {
+	client := Open().SetUrl("http://example.com").SetHeader("X-Token", "abc")
+	client.Close()
+	if atomic.LoadInt32(&client.idle) != 1 {
+		t.Errorf("client should be idle after Close")
+	}
+	// 重复 Close 是空操作
+	client.Close()
+	if atomic.LoadInt32(&client.idle) != 1 {
+		t.Errorf("client should still be idle after closing twice")
+	}
+
+	// 重复 Close 不能导致同一个 Client 被放回连接池两次：连续从连接池中取出的两个 Client 不能相同
+	a, b := idleClients.Get().(*Client), idleClients.Get().(*Client)
+	if a == b {
+		t.Errorf("client should not be returned to the pool twice")
+	}
+	idleClients.Put(a)
+	idleClients.Put(b)
+
+	// 重新打开的 Client（可能是同一个对象）状态被重置
+	c := Open()
+	defer c.Close()
+	if atomic.LoadInt32(&c.idle) != 0 || c.Url() != "" || len(c.Headers()) != 0 {
+		t.Errorf("reopened client should be reset, idle=%v, url=%v, headers=%v", c.idle, c.Url(), c.Headers())
+	}
+}
+
+func TestOpen_Reset(t *testing.T) {
+	client := Open().SetUrl("http://example.com").SetHeader("X-Token", "abc").SetTimeout(1)
+	client.Close()
+	for i := 0; i < 10; i++ {
+		c := Open()
+		if c.Url() != "" || len(c.Headers()) != 0 || c.Timeout != 0 || c.Profile() != DefaultProfile {
+			t.Errorf("client should be reset, url=%v, headers=%v, timeout=%v", c.Url(), c.Headers(), c.Timeout)
+		}
+		defer c.Close()
+	}
+}
+
+// ------------------------------------------------------------------------------ benchmark
+// 旧版本的连接池（全局锁 + 链表线性查找空闲 Client），用于与 sync.Pool 实现对比性能
+type legacyEntry struct {
+	client *Client
+	idle   bool
+}
+
+var (
+	legacyClients = singlylinkedlist.New()
+	legacyLock    sync.Mutex
+)
+
+func legacyOpen() *legacyEntry {
+	legacyLock.Lock()
+	defer legacyLock.Unlock()
+	it := legacyClients.Iterator()
+	for it.Next() {
+		if entry := it.Value().(*legacyEntry); entry.idle {
+			entry.client.profile = getProfile("")
+			entry.client.Reborn()
+			entry.idle = false
+			return entry
+		}
+	}
+	entry := &legacyEntry{client: (&Client{Client: &http.Client{}, fromPool: true, profile: getProfile("")}).Reborn()}
+	legacyClients.Add(entry)
+	return entry
+}
+
+func (this *legacyEntry) Close() {
+	legacyLock.Lock()
+	defer legacyLock.Unlock()
+	this.idle = true
+}
+
+func BenchmarkPool_Legacy(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			legacyOpen().Close()
+		}
+	})
+}
+
+func BenchmarkPool_SyncPool(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Open().Close()
+		}
+	})
+}
+
+// 同时持有多个 Client 时，旧版本需要线性查找空闲的 Client
+func BenchmarkPool_LegacyHeld(b *testing.B) {
+	held := make([]*legacyEntry, 256)
+	for i := range held {
+		held[i] = legacyOpen()
+	}
+	defer func() {
+		for _, v := range held {
+			v.Close()
+		}
+	}()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			legacyOpen().Close()
+		}
+	})
+}
+
+func BenchmarkPool_SyncPoolHeld(b *testing.B) {
+	held := make([]*Client, 256)
+	for i := range held {
+		held[i] = Open()
+	}
+	defer func() {
+		for _, v := range held {
+			v.Close()
+		}
+	}()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Open().Close()
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package httpClient
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProfile_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	if err := RegisterProfile("test-fast", &ClientConfig{Timeout: 50 * time.Millisecond}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+
+	fast := Open("test-fast").SetUrl(server.URL).Get()
+	defer fast.Close()
+	if !IsTimeoutError(fast.Error()) {
+		t.Errorf("expect timeout error, got %v", fast.Error())
+	}
+
+	// 其他 profile 不受影响
+	normal := Open().SetUrl(server.URL).Get()
+	defer normal.Close()
+	if err := normal.Error(); err != nil {
+		t.Errorf("default profile should not be affected: %v", err)
+	}
+	if http.DefaultClient.Timeout != 0 || http.DefaultTransport.(*http.Transport).ResponseHeaderTimeout != 0 {
+		t.Errorf("http.DefaultClient should not be modified")
+	}
+
+	// SetTimeout 只影响当前 Client
+	client := Open().SetUrl(server.URL).SetTimeout(50 * time.Millisecond).Get()
+	if !IsTimeoutError(client.Error()) {
+		t.Errorf("expect timeout error, got %v", client.Error())
+	}
+	client.Close()
+	client = Open().SetUrl(server.URL).Get()
+	if err := client.Error(); err != nil {
+		t.Errorf("SetTimeout should be reset after Close: %v", err)
+	}
+	client.Close()
+}
+
+func TestProfile_SetDefaultTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	original := GetProfile(DefaultProfile)
+	defer RegisterProfile(DefaultProfile, original)
+	if err := RegisterProfile(DefaultProfile, &ClientConfig{RateLimit: &RateLimitOptions{Rate: 0.1, Burst: 1, FailFast: true}}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	limiter := getProfile(DefaultProfile).limiter
+	client := Open().SetUrl(server.URL).Get()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	client.Close()
+
+	// 只修改超时参数，限流器以及令牌桶的状态保持不变
+	SetDefaultTimeout(time.Second)
+	if cfg := GetProfile(DefaultProfile); cfg.Timeout != time.Second || cfg.DialTimeout != time.Second || cfg.RateLimit == nil {
+		t.Errorf("assert faild: %+v", cfg)
+	}
+	if getProfile(DefaultProfile).limiter != limiter {
+		t.Errorf("limiter should not be replaced")
+	}
+	client = Open().SetUrl(server.URL).SetRetryPolicy(NoRetry).Get()
+	var rateLimitErr *RateLimitError
+	if !errors.As(client.Error(), &rateLimitErr) {
+		t.Errorf("expect *RateLimitError, got %v", client.Error())
+	}
+	client.Close()
+}
+
+func TestProfile_NotExist(t *testing.T) {
+	client := Open("not-exist").SetUrl("http://127.0.0.1:1").Get()
+	defer client.Close()
+	if client.Error() == nil {
+		t.Errorf("expect error")
+	}
+}
+
+func TestProfile_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// 不信任自签名证书
+	client := Open().SetUrl(server.URL).SetRetryPolicy(NoRetry).Get()
+	if client.Error() == nil {
+		t.Errorf("expect certificate error")
+	}
+	client.Close()
+
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	RegisterProfile("test-tls", &ClientConfig{TLS: &TLSConfig{CAPem: caPem}})
+	client = Open("test-tls").SetUrl(server.URL).Get()
+	if err := client.Error(); err != nil || client.ResponseText() != "ok" {
+		t.Errorf("assert faild: %v", err)
+	}
+	client.Close()
+
+	RegisterProfile("test-insecure", &ClientConfig{TLS: &TLSConfig{InsecureSkipVerify: true}})
+	client = Open("test-insecure").SetUrl(server.URL).Get()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	client.Close()
+}
+
+func TestProfile_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	if err := RegisterProfile("test-proxy", &ClientConfig{Proxy: proxy.URL}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	client := Open("test-proxy").SetUrl("http://api.example.invalid/abc").Get()
+	defer client.Close()
+	if text := client.ResponseText(); text != "proxied http://api.example.invalid/abc" {
+		t.Errorf("assert faild: %v, %v", text, client.Error())
+	}
+
+	if err := RegisterProfile("test-proxy", &ClientConfig{Proxy: "ftp://127.0.0.1"}); err == nil {
+		t.Errorf("expect error for unsupported proxy")
+	}
+}
@@ -0,0 +1,212 @@
+package httpClient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type RateLimitOptions struct {
+	Rate     float64       // 每秒允许的请求数（令牌的产生速度），<=0 表示不限制
+	Burst    int           // 令牌桶的容量（允许的突发请求数），默认为 Rate 向上取整（至少为 1）
+	FailFast bool          // 没有可用的令牌时是否立即返回 *RateLimitError。为 false 时等待令牌，但需要等待的时间超过 context 的 deadline 时也会立即返回
+	MaxWait  time.Duration // 最长等待时间，需要等待更长时间时返回 *RateLimitError，0 表示不限制
+}
+
+// 被限流时返回的错误
+type RateLimitError struct {
+	Key  string        // 限流的 Key（Host 或者 profile 名称）
+	Wait time.Duration // 需要等待多长时间才有可用的令牌
+}
+
+func (this *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: %v, need to wait %v", this.Key, this.Wait)
+}
+
+// 基于令牌桶的客户端限流器，每次（包括重试）请求消耗一个令牌。
+// 通过 AddInterceptor(l.Interceptor()) 注册为全局拦截器，通过 Client.SetRateLimiter 对单个 Client 生效，或者通过 ClientConfig.RateLimit 对 profile 生效。
+// 等待令牌以及被限流的情况可以通过 OnRateLimit 监听，每次请求等待的总时长可以通过 Client.RateLimitWait 获取。
+type RateLimiter struct {
+	name    string
+	opt     RateLimitOptions
+	perHost bool
+	hosts   map[string]*RateLimitOptions // 单独设置的 Host 的限流参数
+	buckets map[string]*tokenBucket
+	lock    sync.Mutex
+}
+
+type tokenBucket struct {
+	opt    *RateLimitOptions
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// 创建一个限流器，所有请求共用同一个令牌桶
+func NewRateLimiter(opt *RateLimitOptions) *RateLimiter {
+	return newRateLimiter("", opt, false)
+}
+
+// 创建一个按 Host（scheme://host:port）分别限流的限流器，每个 Host 使用独立的令牌桶。
+// opt 为所有 Host 的默认参数（opt.Rate<=0 时只限制通过 SetHostLimit 设置过的 Host）。
+func NewHostRateLimiter(opt *RateLimitOptions) *RateLimiter {
+	return newRateLimiter("", opt, true)
+}
+
+func newRateLimiter(name string, opt *RateLimitOptions, perHost bool) *RateLimiter {
+	this := &RateLimiter{name: name, perHost: perHost, hosts: make(map[string]*RateLimitOptions), buckets: make(map[string]*tokenBucket)}
+	if opt != nil {
+		this.opt = *opt
+	}
+	return this
+}
+
+// 单独设置指定 Host（scheme://host:port，如 https://api.example.com）的限流参数，opt 为 nil 时恢复为默认参数。只对 NewHostRateLimiter 创建的限流器有效。
+func (this *RateLimiter) SetHostLimit(host string, opt *RateLimitOptions) *RateLimiter {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if opt == nil {
+		delete(this.hosts, host)
+	} else {
+		v := *opt
+		this.hosts[host] = &v
+	}
+	return this
+}
+
+// 获取当前可用的令牌数（小于 0 表示有请求正在等待）。key 为 Host（scheme://host:port），所有请求共用令牌桶时忽略 key。
+func (this *RateLimiter) Tokens(key string) float64 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if !this.perHost {
+		key = this.name
+	}
+	opt := this.options(key)
+	if opt.Rate <= 0 {
+		return math.Inf(1)
+	}
+	b := this.bucket(key, opt)
+	b.refill(time.Now())
+	return b.tokens
+}
+
+// 获取限流器对应的拦截器
+func (this *RateLimiter) Interceptor() Interceptor {
+	return func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		key := this.name
+		if this.perHost {
+			key = req.URL.Scheme + "://" + req.URL.Host
+		}
+		wait, err := this.wait(req.Context(), key)
+		c.statsLock.Lock()
+		c.rateLimitWait += wait
+		c.statsLock.Unlock()
+		if wait > 0 || err != nil {
+			fireRateLimit(c, key, wait, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return next(req)
+	}
+}
+
+// 等待可用的令牌，返回实际等待的时间
+func (this *RateLimiter) wait(ctx context.Context, key string) (time.Duration, error) {
+	this.lock.Lock()
+	opt := this.options(key)
+	if opt.Rate <= 0 {
+		this.lock.Unlock()
+		return 0, nil
+	}
+	b := this.bucket(key, opt)
+	now := time.Now()
+	wait := b.reserve(now)
+	if wait > 0 {
+		deadline, ok := ctx.Deadline()
+		if opt.FailFast || (opt.MaxWait > 0 && wait > opt.MaxWait) || (ok && now.Add(wait).After(deadline)) {
+			b.cancel(now)
+			this.lock.Unlock()
+			return 0, &RateLimitError{Key: key, Wait: wait}
+		}
+	}
+	this.lock.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+	if !sleepCtx(ctx, wait) {
+		this.lock.Lock()
+		b.cancel(time.Now())
+		this.lock.Unlock()
+		return time.Since(now), ctx.Err()
+	}
+	return wait, nil
+}
+
+// 获取 key 对应的限流参数（调用时需要持有锁）
+func (this *RateLimiter) options(key string) *RateLimitOptions {
+	if this.perHost {
+		if opt := this.hosts[key]; opt != nil {
+			return opt
+		}
+	}
+	return &this.opt
+}
+
+// 获取 key 对应的令牌桶，限流参数变化时重新创建（调用时需要持有锁）
+func (this *RateLimiter) bucket(key string, opt *RateLimitOptions) *tokenBucket {
+	b := this.buckets[key]
+	if b == nil || b.opt != opt {
+		burst := float64(opt.Burst)
+		if burst <= 0 {
+			burst = math.Max(1, math.Ceil(opt.Rate))
+		}
+		b = &tokenBucket{opt: opt, rate: opt.Rate, burst: burst, tokens: burst, last: time.Now()}
+		this.buckets[key] = b
+	}
+	return b
+}
+
+func (this *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(this.last); elapsed > 0 {
+		this.tokens = math.Min(this.burst, this.tokens+elapsed.Seconds()*this.rate)
+		this.last = now
+	}
+}
+
+// 预订一个令牌，返回需要等待的时间
+func (this *tokenBucket) reserve(now time.Time) time.Duration {
+	this.refill(now)
+	this.tokens--
+	if this.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-this.tokens / this.rate * float64(time.Second))
+}
+
+// 归还预订的令牌
+func (this *tokenBucket) cancel(now time.Time) {
+	this.refill(now)
+	this.tokens = math.Min(this.burst, this.tokens+1)
+}
+
+// 设置当前 Client 使用的限流器。等同于 AddInterceptor(l.Interceptor())
+func (this *Client) SetRateLimiter(l *RateLimiter) *Client {
+	if l != nil {
+		this.AddInterceptor(l.Interceptor())
+	}
+	return this
+}
+
+// 获取上一次 Request（包括重试）因为限流而等待的总时间
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) RateLimitWait() time.Duration {
+	this.statsLock.Lock()
+	defer this.statsLock.Unlock()
+	return this.rateLimitWait
+}
@@ -0,0 +1,118 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var events int
+	rateLimitHandler = nil
+	defer func() { rateLimitHandler = nil }()
+	OnRateLimit(func(c *Client, key string, wait time.Duration, err error) {
+		events++
+	})
+
+	limiter := NewRateLimiter(&RateLimitOptions{Rate: 20, Burst: 1})
+	begin := time.Now()
+	var waited time.Duration
+	for i := 0; i < 3; i++ {
+		client := Open().SetUrl(server.URL).SetRateLimiter(limiter).Get()
+		if err := client.Error(); err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+		waited += client.RateLimitWait()
+		client.Close()
+	}
+	if took := time.Since(begin); took < 90*time.Millisecond {
+		t.Errorf("rate limit not honored, took %v", took)
+	}
+	if waited < 50*time.Millisecond || events != 2 {
+		t.Errorf("assert faild, waited=%v, events=%v", waited, events)
+	}
+}
+
+func TestRateLimiter_FailFast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(&RateLimitOptions{Rate: 1, Burst: 1, FailFast: true})
+	client := Open().SetUrl(server.URL).SetRateLimiter(limiter)
+	defer client.Close()
+	if err := client.Get().Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	var rateLimitErr *RateLimitError
+	if err := client.Get().Error(); !errors.As(err, &rateLimitErr) {
+		t.Errorf("expect *RateLimitError, got %v", err)
+	} else if client.Attempts() != 1 {
+		t.Errorf("rate limited request should not be retried, got %v attempts", client.Attempts())
+	}
+
+	// 需要等待的时间超过 context 的 deadline 时立即返回
+	limiter = NewRateLimiter(&RateLimitOptions{Rate: 1, Burst: 1})
+	client.Reborn().SetUrl(server.URL).SetRateLimiter(limiter).Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := client.GetCtx(ctx).Error(); !errors.As(err, &rateLimitErr) {
+		t.Errorf("expect *RateLimitError, got %v", err)
+	} else if took := time.Since(begin); took > 50*time.Millisecond {
+		t.Errorf("should fail fast before deadline, took %v", took)
+	}
+}
+
+func TestRateLimiter_Host(t *testing.T) {
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server1.Close()
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server2.Close()
+
+	limiter := NewHostRateLimiter(nil).SetHostLimit(server1.URL, &RateLimitOptions{Rate: 1, Burst: 2, FailFast: true})
+	client := Open().SetRateLimiter(limiter)
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		if err := client.SetUrl(server2.URL).Get().Error(); err != nil {
+			t.Errorf("unlimited host: error occured: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.SetUrl(server1.URL).Get().Error(); err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+	}
+	if client.SetUrl(server1.URL).Get().Error() == nil {
+		t.Errorf("expect rate limit error")
+	}
+	if v := limiter.Tokens(server1.URL); v >= 1 {
+		t.Errorf("assert faild, tokens=%v", v)
+	}
+}
+
+func TestRateLimiter_Profile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if err := RegisterProfile("test-ratelimit", &ClientConfig{RateLimit: &RateLimitOptions{Rate: 1, Burst: 1, FailFast: true}}); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	client1 := Open("test-ratelimit").SetUrl(server.URL).Get()
+	defer client1.Close()
+	client2 := Open("test-ratelimit").SetUrl(server.URL).Get()
+	defer client2.Close()
+	var rateLimitErr *RateLimitError
+	if err := client1.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	if err := client2.Error(); !errors.As(err, &rateLimitErr) || rateLimitErr.Key != "test-ratelimit" {
+		t.Errorf("clients of the same profile should share the limit, got %v", err)
+	}
+}
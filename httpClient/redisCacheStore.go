@@ -0,0 +1,55 @@
+package httpClient
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// 基于 Redis 的缓存存储，用于在多个进程之间共享 Response 缓存（此时请设置 CacheOptions.Shared）。
+// 读写 Redis 失败时视为没有缓存，不会影响请求。
+type RedisCacheStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// 创建基于 Redis 的缓存存储，prefix 为 Redis key 的前缀（实际的 key 为 prefix + md5(缓存的 key)）
+func NewRedisCacheStore(client redis.Cmdable, prefix string) *RedisCacheStore {
+	return &RedisCacheStore{client: client, prefix: prefix}
+}
+
+func (this *RedisCacheStore) Get(key string) *CachedResponse {
+	data, err := this.client.Get(this.redisKey(key)).Bytes()
+	if err != nil {
+		return nil
+	}
+	v := &CachedResponse{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil
+	}
+	return v
+}
+
+func (this *RedisCacheStore) Set(key string, v *CachedResponse, ttl time.Duration) {
+	if v == nil || ttl <= 0 {
+		this.Delete(key)
+		return
+	}
+	// http.Header 是 map 类型，使用标准库序列化
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	this.client.Set(this.redisKey(key), data, ttl)
+}
+
+func (this *RedisCacheStore) Delete(key string) {
+	this.client.Del(this.redisKey(key))
+}
+
+func (this *RedisCacheStore) redisKey(key string) string {
+	sum := md5.Sum([]byte(key))
+	return this.prefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 重试策略。
+// 每次请求结束（且未成功）之后调用 ShouldRetry 判断是否需要重试，调用时可以通过 c.StatusCode、c.Error()、c.ResponseHeader() 等获取本次请求的结果。
+//   attempt: 已经发起的请求次数，从 1 开始
+//   返回值: 是否需要重试，以及重试之前需要等待的时间
+type RetryPolicy interface {
+	ShouldRetry(c *Client, attempt int) (bool, time.Duration)
+}
+
+// 指数退避的重试策略：第 n 次重试前等待 Interval * Multiplier^(n-1)，并增加随机抖动，防止大量请求在同一时刻重试。
+// 对于 429、503 的返回结果，如果服务端通过 Retry-After 指定了等待时间，则以服务端指定的时间为准。
+type BackoffRetry struct {
+	MaxAttempts        int           // 最大请求次数（包含第一次请求），<=1 表示不重试
+	Interval           time.Duration // 第一次重试前的等待时间
+	MaxInterval        time.Duration // 重试前等待时间的上限，<=0 表示不限制
+	Multiplier         float64       // 每次重试后等待时间的增长倍数，<=1 表示固定间隔
+	Jitter             float64       // 随机抖动的比例，取值 [0, 1]。实际等待时间在 [interval*(1-Jitter), interval*(1+Jitter)] 之间
+	MaxRetryAfter      time.Duration // 允许服务端通过 Retry-After 指定的最长等待时间，超过则不重试。<=0 表示不限制
+	RetryNonIdempotent bool          // 是否重试非幂等的请求（POST、PATCH 等）。默认不重试，除非请求中设置了 Idempotency-Key
+}
+
+var (
+	// 默认的重试策略：最多请求 3 次，重试间隔从 100ms 开始指数增长
+	DefaultRetryPolicy RetryPolicy = NewBackoffRetry(3, 100*time.Millisecond)
+
+	// 不重试
+	NoRetry RetryPolicy = &BackoffRetry{MaxAttempts: 1}
+)
+
+// 创建一个指数退避的重试策略，其他参数使用默认值：增长倍数 2，抖动比例 0.2，等待时间上限 5 秒，Retry-After 上限 10 秒
+func NewBackoffRetry(maxAttempts int, interval time.Duration) *BackoffRetry {
+	return &BackoffRetry{
+		MaxAttempts:   maxAttempts,
+		Interval:      interval,
+		MaxInterval:   5 * time.Second,
+		Multiplier:    2,
+		Jitter:        0.2,
+		MaxRetryAfter: 10 * time.Second,
+	}
+}
+
+// 创建一个固定间隔、没有随机抖动的重试策略
+func NewFixedRetry(maxAttempts int, interval time.Duration) *BackoffRetry {
+	return &BackoffRetry{MaxAttempts: maxAttempts, Interval: interval}
+}
+
+func (this *BackoffRetry) ShouldRetry(c *Client, attempt int) (bool, time.Duration) {
+	if attempt >= this.MaxAttempts {
+		return false, 0
+	}
+	if !this.RetryNonIdempotent && !isIdempotent(c.method, c.headers) {
+		return false, 0
+	}
+
+	if err := c.err; err != nil && c.StatusCode <= 0 {
+		// 请求没有得到响应
+		if !IsRetryableError(err) {
+			return false, 0
+		}
+	} else if !IsRetryableStatus(c.StatusCode) {
+		return false, 0
+	}
+
+	if c.StatusCode == http.StatusTooManyRequests || c.StatusCode == http.StatusServiceUnavailable {
+		if wait, ok := parseRetryAfter(c.responseHeader.Get("Retry-After")); ok {
+			if this.MaxRetryAfter > 0 && wait > this.MaxRetryAfter {
+				return false, 0
+			}
+			return true, wait
+		}
+	}
+
+	return true, this.backoff(attempt)
+}
+
+// 计算第 attempt 次请求失败之后，重试前需要等待的时间
+func (this *BackoffRetry) backoff(attempt int) time.Duration {
+	interval := float64(this.Interval)
+	if this.Multiplier > 1 {
+		for i := 1; i < attempt; i++ {
+			interval *= this.Multiplier
+			if this.MaxInterval > 0 && interval >= float64(this.MaxInterval) {
+				break
+			}
+		}
+	}
+	if this.MaxInterval > 0 && interval > float64(this.MaxInterval) {
+		interval = float64(this.MaxInterval)
+	}
+	if this.Jitter > 0 {
+		jitter := this.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		interval = interval * (1 - jitter + 2*jitter*rand.Float64())
+	}
+	return time.Duration(interval)
+}
+
+// 判断请求过程中发生的错误（没有得到服务端响应）是否可以重试。超时、context 结束、熔断以及限流的错误不重试。
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsTimeoutError(err) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var circuitErr *CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		return false
+	}
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return false
+	}
+	return true
+}
+
+// 判断服务端返回的状态码是否可以重试：429 以及除 501（Not Implemented）、504（Gateway Timeout）之外的 5xx
+func IsRetryableStatus(status int) bool {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return true
+	case status == http.StatusNotImplemented || status == http.StatusGatewayTimeout:
+		return false
+	case status >= 500:
+		return true
+	}
+	return false
+}
+
+// 判断错误是否是超时错误
+func IsTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// 判断请求是否是幂等的。POST、PATCH 等请求如果设置了 Idempotency-Key 也视为幂等请求
+func isIdempotent(method string, headers map[string]string) bool {
+	switch strings.ToUpper(method) {
+	case "", "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return getHeader(headers, "Idempotency-Key") != ""
+}
+
+// 解析 Retry-After，支持秒数和 HTTP-date 两种格式
+func parseRetryAfter(s string) (time.Duration, bool) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
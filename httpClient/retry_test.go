@@ -0,0 +1,125 @@
+package httpClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_RetryPolicy(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(502)
+		} else {
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL).SetRetryPolicy(NewBackoffRetry(3, 10*time.Millisecond)).Get()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if client.Attempts() != 3 || client.ResponseText() != "ok" {
+		t.Errorf("assert faild, attempts=%v, text=%v", client.Attempts(), client.ResponseText())
+	}
+}
+
+func TestClient_RetryNonIdempotent(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL).SetBody("abc").SetRetry(3, time.Millisecond).Post()
+	client.Close()
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Errorf("POST should not be retried by default, got %v attempts", n)
+	}
+
+	atomic.StoreInt32(&count, 0)
+	client = Open().SetUrl(server.URL).SetBody("abc").SetHeader("idempotency-key", "123").SetRetry(3, time.Millisecond).Post()
+	client.Close()
+	if n := atomic.LoadInt32(&count); n != 3 {
+		t.Errorf("POST with Idempotency-Key should be retried, got %v attempts", n)
+	}
+
+	atomic.StoreInt32(&count, 0)
+	policy := NewFixedRetry(3, time.Millisecond)
+	policy.RetryNonIdempotent = true
+	client = Open().SetUrl(server.URL).SetBody("abc").SetRetryPolicy(policy).Post()
+	client.Close()
+	if n := atomic.LoadInt32(&count); n != 3 {
+		t.Errorf("POST should be retried after opt in, got %v attempts", n)
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(429)
+		}
+	}))
+	defer server.Close()
+
+	begin := time.Now()
+	client := Open().SetUrl(server.URL).SetRetryPolicy(NewBackoffRetry(3, time.Millisecond)).Get()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if took := time.Since(begin); took < time.Second {
+		t.Errorf("Retry-After not honored, took %v", took)
+	}
+
+	policy := NewBackoffRetry(3, time.Millisecond)
+	policy.MaxRetryAfter = 500 * time.Millisecond
+	atomic.StoreInt32(&count, 0)
+	client2 := Open().SetUrl(server.URL).SetRetryPolicy(policy).Get()
+	defer client2.Close()
+	if client2.StatusCode != 429 || client2.Attempts() != 1 {
+		t.Errorf("Retry-After above limit should not retry, status=%v, attempts=%v", client2.StatusCode, client2.Attempts())
+	}
+}
+
+func TestClient_NoRetryOnTimeout(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := (&Client{Client: &http.Client{}}).Reborn()
+	client.Timeout = 50 * time.Millisecond
+	client.SetUrl(server.URL).SetRetryPolicy(NewBackoffRetry(3, time.Millisecond)).Get()
+	if !IsTimeoutError(client.Error()) {
+		t.Errorf("expect timeout error, got %v", client.Error())
+	}
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Errorf("timeout should not be retried, got %v attempts", n)
+	}
+}
+
+func TestBackoffRetry_Backoff(t *testing.T) {
+	policy := &BackoffRetry{Interval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	expects := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, expect := range expects {
+		if v := policy.backoff(i + 1); v != expect {
+			t.Errorf("attempt %v: expect %v, got %v", i+1, expect, v)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if v := policy.backoff(1); v < 50*time.Millisecond || v > 150*time.Millisecond {
+			t.Errorf("jitter out of range: %v", v)
+		}
+	}
+}
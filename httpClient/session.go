@@ -0,0 +1,210 @@
+package httpClient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"yelo/go-util/jsonUtil"
+)
+
+// 会话，实现了 http.CookieJar 接口，用于在多次请求之间保持服务端设置的 Cookie。
+// Cookie 按照 Domain、Path 的规则（参考 RFC 6265）匹配，只会发送给对应的域名。可以同时被多个（从连接池中打开的）Client 使用。
+// 通过 Export、Import 可以将会话保存为 Json 格式并在之后恢复。
+type Session struct {
+	jar     *cookiejar.Jar
+	entries map[string]*SessionCookie // 用来导出的 Cookie 记录，key 为 domain;path;name
+	lock    sync.Mutex
+}
+
+// 导出的 Cookie
+type SessionCookie struct {
+	Url      string     `json:"url"` // 设置 Cookie 的请求的 Url（只包含 scheme://host/）
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"` // 为空表示只发送给设置 Cookie 的 Host
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"` // nil 表示会话 Cookie
+	Secure   bool       `json:"secure,omitempty"`
+	HttpOnly bool       `json:"httpOnly,omitempty"`
+}
+
+// 创建一个空的会话
+func NewSession() *Session {
+	jar, _ := cookiejar.New(nil)
+	return &Session{jar: jar, entries: make(map[string]*SessionCookie)}
+}
+
+// 实现 http.CookieJar 接口
+func (this *Session) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, v := range cookies {
+		domain := strings.TrimPrefix(strings.ToLower(v.Domain), ".")
+		if domain == "" {
+			domain = strings.ToLower(u.Hostname())
+		}
+		path := v.Path
+		if path == "" || path[0] != '/' {
+			path = defaultCookiePath(u.Path)
+		}
+		key := domain + ";" + path + ";" + v.Name
+		if v.MaxAge < 0 || (!v.Expires.IsZero() && !v.Expires.After(now)) {
+			delete(this.entries, key)
+			continue
+		}
+		if !this.accepted(domain, path, v) {
+			// 被 cookiejar 拒绝的 Cookie（如 Domain 与请求的 Host 不匹配）不会被发送，也不记录
+			continue
+		}
+		entry := &SessionCookie{Url: u.Scheme + "://" + u.Host + "/", Name: v.Name, Value: v.Value, Domain: v.Domain, Path: path, Secure: v.Secure, HttpOnly: v.HttpOnly}
+		if v.MaxAge > 0 {
+			expires := now.Add(time.Duration(v.MaxAge) * time.Second)
+			entry.Expires = &expires
+		} else if !v.Expires.IsZero() {
+			expires := v.Expires
+			entry.Expires = &expires
+		}
+		this.entries[key] = entry
+	}
+}
+
+// 检查 Cookie 是否被 cookiejar 接受（请求 Cookie 对应的 Url 时会发送该 Cookie）。调用者需持有锁
+func (this *Session) accepted(domain, path string, cookie *http.Cookie) bool {
+	u := &url.URL{Scheme: "http", Host: domain, Path: path}
+	if cookie.Secure {
+		u.Scheme = "https"
+	}
+	for _, v := range this.jar.Cookies(u) {
+		if v.Name == cookie.Name && v.Value == cookie.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// 实现 http.CookieJar 接口
+func (this *Session) Cookies(u *url.URL) []*http.Cookie {
+	this.lock.Lock()
+	jar := this.jar
+	this.lock.Unlock()
+	return jar.Cookies(u)
+}
+
+// 手动设置 Cookie，rawUrl 为 Cookie 所属的 Url，如 https://example.com/
+func (this *Session) SetCookie(rawUrl string, cookies ...*http.Cookie) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return err
+	}
+	this.SetCookies(u, cookies)
+	return nil
+}
+
+// 获取请求 rawUrl 时会发送的 Cookie 的值，不存在时返回空字符串
+func (this *Session) GetCookie(rawUrl string, name string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	for _, v := range this.Cookies(u) {
+		if v.Name == name {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// 清除所有 Cookie
+func (this *Session) Clear() {
+	jar, _ := cookiejar.New(nil)
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.jar = jar
+	this.entries = make(map[string]*SessionCookie)
+}
+
+// 将会话中（未过期的）Cookie 导出为 Json 格式
+func (this *Session) Export() ([]byte, error) {
+	return jsonUtil.Marshal(this.List())
+}
+
+// 获取会话中所有未过期的 Cookie（按照 Domain、Path、Name 排序）
+func (this *Session) List() []*SessionCookie {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	now := time.Now()
+	keys := make([]string, 0, len(this.entries))
+	for key, v := range this.entries {
+		if v.Expires != nil && !v.Expires.After(now) {
+			delete(this.entries, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	arr := make([]*SessionCookie, len(keys))
+	for i, key := range keys {
+		v := *this.entries[key]
+		arr[i] = &v
+	}
+	return arr
+}
+
+// 导入通过 Export 导出的 Cookie（与已经存在的 Cookie 合并）
+func (this *Session) Import(data []byte) error {
+	var arr []*SessionCookie
+	if err := jsonUtil.Unmarshal(data, &arr); err != nil {
+		return fmt.Errorf("会话数据格式错误: %v", err)
+	}
+	for _, v := range arr {
+		if v == nil {
+			continue
+		}
+		u, err := url.Parse(v.Url)
+		if err != nil {
+			return fmt.Errorf("会话数据格式错误: %v", err)
+		}
+		cookie := &http.Cookie{Name: v.Name, Value: v.Value, Domain: v.Domain, Path: v.Path, Secure: v.Secure, HttpOnly: v.HttpOnly}
+		if v.Expires != nil {
+			cookie.Expires = *v.Expires
+		}
+		this.SetCookies(u, []*http.Cookie{cookie})
+	}
+	return nil
+}
+
+// 设置当前 Client 使用的会话：请求时自动发送会话中的 Cookie，并保存服务端返回的 Set-Cookie。调用 Reborn（包括从连接池中重新打开）时清除。
+func (this *Client) SetSession(s *Session) *Client {
+	if s == nil {
+		this.Jar = nil
+	} else {
+		this.Jar = s
+	}
+	return this
+}
+
+// 获取当前 Client 使用的会话，未设置时返回 nil
+// 请在调用 Close（归还到连接池）方法之前调用，因为归还到连接池之后有可能由于被其他线程取出而被重置参数。
+func (this *Client) Session() *Session {
+	s, _ := this.Jar.(*Session)
+	return s
+}
+
+// Cookie 的默认 Path（参考 RFC 6265 5.1.4）
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		return path[:i]
+	}
+	return "/"
+}
@@ -0,0 +1,120 @@
+package httpClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_Session(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "123", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "tmp", Value: "abc", Path: "/login"})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "sid", MaxAge: -1, Path: "/"})
+		default:
+			if c, err := r.Cookie("sid"); err == nil {
+				w.Write([]byte(c.Value))
+			}
+			if _, err := r.Cookie("tmp"); err == nil {
+				w.Write([]byte(" tmp"))
+			}
+		}
+	}))
+	defer server.Close()
+
+	session := NewSession()
+	client := Open().SetSession(session).SetUrl(server.URL + "/login").Get()
+	client.Close()
+
+	// 其他 Client 使用同一个会话
+	client2 := Open().SetSession(session).SetUrl(server.URL + "/profile").Get()
+	defer client2.Close()
+	if err := client2.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if client2.ResponseText() != "123" {
+		t.Errorf("assert faild: %v", client2.ResponseText())
+	}
+
+	// 不使用会话的 Client 不发送 Cookie
+	if text := client2.Reborn().SetUrl(server.URL + "/profile").Get().ResponseText(); text != "" {
+		t.Errorf("cookie should not be sent without session, got %v", text)
+	}
+
+	client2.Reborn().SetSession(session).SetUrl(server.URL + "/logout").Get()
+	if v := session.GetCookie(server.URL+"/", "sid"); v != "" {
+		t.Errorf("cookie should be deleted, got %v", v)
+	}
+}
+
+func TestSession_Scope(t *testing.T) {
+	session := NewSession()
+	session.SetCookie("http://a.example.com/", &http.Cookie{Name: "host", Value: "1"})
+	session.SetCookie("http://a.example.com/", &http.Cookie{Name: "domain", Value: "2", Domain: "example.com"})
+	session.SetCookie("https://a.example.com/", &http.Cookie{Name: "secure", Value: "3", Secure: true})
+
+	if session.GetCookie("http://a.example.com/", "host") != "1" || session.GetCookie("http://b.example.com/", "host") != "" {
+		t.Errorf("host-only cookie should only be sent to its host")
+	}
+	if session.GetCookie("http://b.example.com/", "domain") != "2" || session.GetCookie("http://example.org/", "domain") != "" {
+		t.Errorf("domain cookie should be sent to sub domains")
+	}
+	if session.GetCookie("http://a.example.com/", "secure") != "" || session.GetCookie("https://a.example.com/", "secure") != "3" {
+		t.Errorf("secure cookie should only be sent over https")
+	}
+
+	// 被 cookiejar 拒绝的 Cookie 不会被导出
+	session.SetCookie("http://a.example.com/", &http.Cookie{Name: "foreign", Value: "4", Domain: "other.com"})
+	if session.GetCookie("http://other.com/", "foreign") != "" {
+		t.Errorf("foreign cookie should be rejected")
+	}
+	for _, v := range session.List() {
+		if v.Name == "foreign" {
+			t.Errorf("rejected cookie should not be listed: %+v", v)
+		}
+	}
+	if n := len(session.List()); n != 3 {
+		t.Errorf("assert faild: %v", n)
+	}
+}
+
+func TestSession_ExportImport(t *testing.T) {
+	session := NewSession()
+	session.SetCookie("http://a.example.com/api/login", &http.Cookie{Name: "sid", Value: "123"})
+	session.SetCookie("http://a.example.com/", &http.Cookie{Name: "domain", Value: "2", Domain: ".example.com", MaxAge: 3600})
+	data, err := session.Export()
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+
+	session2 := NewSession()
+	if err := session2.Import(data); err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	if v := session2.List(); len(v) != 2 {
+		t.Errorf("assert faild: %s", data)
+	}
+	// 会话 Cookie 不导出过期时间
+	if strings.Count(string(data), `"expires"`) != 1 {
+		t.Errorf("assert faild: %s", data)
+	}
+	if session2.GetCookie("http://a.example.com/api/orders", "sid") != "123" || session2.GetCookie("http://a.example.com/", "sid") != "" {
+		t.Errorf("path of cookie should be kept: %s", data)
+	}
+	if session2.GetCookie("http://b.example.com/", "domain") != "2" {
+		t.Errorf("domain of cookie should be kept: %s", data)
+	}
+}
+
+func TestClient_RebornClearsCookie(t *testing.T) {
+	client := (&Client{Client: &http.Client{}}).Reborn()
+	client.AddCookie(&http.Cookie{Name: "a", Value: "1"})
+	if client.Reborn(); len(client.RequestParams().Cookie) != 0 {
+		t.Errorf("cookie should be cleared by Reborn")
+	}
+}
@@ -0,0 +1,318 @@
+package httpClient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 用来创建 Request Body 的函数，每次（重试）请求时都会调用该函数重新创建 body。
+// 如果返回的 io.Reader 实现了 io.Closer，则请求结束后会被自动关闭。
+type BodyFunc func() (io.Reader, error)
+
+// 下载进度的回调函数
+//   written: 已经写入文件的字节数（包含断点续传之前已经下载的部分）
+//   total: 文件的总字节数，未知时为 -1
+type ProgressFunc func(written, total int64)
+
+// multipart/form-data 格式的 Request Body。
+// 文件内容在每次（重试）请求时重新读取，不会一次性读入内存。
+type Multipart struct {
+	boundary string
+	parts    []*multipartPart
+}
+
+type multipartPart struct {
+	field    string
+	value    string
+	filename string
+	path     string // 通过 AddFile 增加的文件的路径
+	open     func() (io.Reader, error)
+}
+
+// 创建一个 multipart/form-data 格式的 Request Body
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(ioutil.Discard).Boundary()}
+}
+
+// 增加一个普通字段
+func (this *Multipart) AddField(field, value string) *Multipart {
+	this.parts = append(this.parts, &multipartPart{field: field, value: value})
+	return this
+}
+
+// 增加一个文件，文件名取 path 中的文件名部分
+func (this *Multipart) AddFile(field, path string) *Multipart {
+	this.AddReader(field, filepath.Base(path), func() (io.Reader, error) {
+		return os.Open(path)
+	})
+	this.parts[len(this.parts)-1].path = path
+	return this
+}
+
+// 增加一个文件，文件内容通过 open 函数获取。每次（重试）请求时都会调用 open 重新获取文件内容，
+// 如果返回的 io.Reader 实现了 io.Closer，则读取完毕后会被自动关闭。
+func (this *Multipart) AddReader(field, filename string, open func() (io.Reader, error)) *Multipart {
+	this.parts = append(this.parts, &multipartPart{field: field, filename: filename, open: open})
+	return this
+}
+
+// 获取 Content-Type（包含 boundary）
+func (this *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + this.boundary
+}
+
+func (this *Multipart) String() string {
+	arr := make([]string, 0, len(this.parts))
+	for _, part := range this.parts {
+		if part.open == nil {
+			arr = append(arr, part.field+"="+part.value)
+		} else {
+			arr = append(arr, part.field+"=@"+part.filename)
+		}
+	}
+	return "multipart: " + strings.Join(arr, "&")
+}
+
+// 创建一个读取 multipart 数据的 io.ReadCloser，数据在读取的过程中由后台协程写入。
+// 后台协程在第一次读取时才启动，关闭之后退出，因此没有被读取过的 body 不会造成协程泄漏。
+func (this *Multipart) reader() io.ReadCloser {
+	return &multipartReader{m: this}
+}
+
+type multipartReader struct {
+	m      *Multipart
+	pr     *io.PipeReader
+	closed bool
+	lock   sync.Mutex
+}
+
+func (this *multipartReader) Read(p []byte) (int, error) {
+	this.lock.Lock()
+	if this.closed {
+		this.lock.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if this.pr == nil {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(this.m.writeTo(pw))
+		}()
+		this.pr = pr
+	}
+	pr := this.pr
+	this.lock.Unlock()
+	return pr.Read(p)
+}
+
+func (this *multipartReader) Close() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.closed = true
+	if this.pr != nil {
+		return this.pr.Close()
+	}
+	return nil
+}
+
+func (this *Multipart) writeTo(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(this.boundary); err != nil {
+		return err
+	}
+	for _, part := range this.parts {
+		if part.open == nil {
+			if err := writer.WriteField(part.field, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r, err := part.open()
+		if err != nil {
+			return fmt.Errorf("打开文件 %v 失败: %v", part.filename, err)
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(part.field), escapeQuotes(part.filename)))
+		header.Set("Content-Type", "application/octet-stream")
+		dst, err := writer.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(dst, r)
+		}
+		if closer, ok := r.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+func toReadCloser(r io.Reader) io.ReadCloser {
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc
+	}
+	return ioutil.NopCloser(r)
+}
+
+// 设置 multipart/form-data 格式的 Request Body。等同于 SetBody(m)
+func (this *Client) SetMultipart(m *Multipart) *Client {
+	return this.SetBody(m)
+}
+
+// 设置用来创建 Request Body 的函数，每次（重试）请求时都会调用该函数重新创建 body。等同于 SetBody(BodyFunc(f))
+func (this *Client) SetBodyFunc(f func() (io.Reader, error)) *Client {
+	return this.SetBody(BodyFunc(f))
+}
+
+// （使用已经设置好的 Method、Url、Body、Header 等）以流式方式发起 HTTP 请求。
+// 请求成功时不读取 Response Body，而是直接返回给调用者，调用者读取完毕之后必须调用 Close 关闭。
+// 请求失败时返回 nil 以及对应的错误，此时可以通过 StatusCode、ResponseData() 等获取失败的原因。
+// 注意：http.Client 的 Timeout 同样作用于读取 Response Body 的过程，下载大文件时请设置合适的超时时间或者使用 context 控制。
+func (this *Client) RequestStream() (io.ReadCloser, error) {
+	this.execute(true)
+	body := this.responseBody
+	this.responseBody = nil
+	if this.err != nil {
+		if body != nil {
+			body.Close()
+		}
+		return nil, this.err
+	}
+	return body, nil
+}
+
+// 使用 GET 方式以流式方式发起 HTTP 请求。 等同于 SetMethod("GET").RequestStream()
+func (this *Client) GetStream() (io.ReadCloser, error) {
+	return this.SetMethod("GET").RequestStream()
+}
+
+// 使用 GET 方式下载文件并保存到 path 中。
+// 如果 path 已经存在，则通过 Range 请求从断点处继续下载（服务端不支持 Range 时重新下载整个文件）。
+// 下载中途失败时会保留已经下载的部分，再次调用即可从断点处继续下载。服务端返回的 Content-Range 与断点不符时返回错误，不修改已经下载的部分。
+// 参数 progress 为下载进度的回调函数，可以为 nil。
+func (this *Client) DownloadToFile(path string, progress ProgressFunc) error {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%v 是一个目录", path)
+		}
+		offset = info.Size()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if offset > 0 {
+		this.setHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// 断点续传依赖原始数据的偏移量，不能使用压缩传输
+	this.setHeader("Accept-Encoding", "identity")
+
+	body, err := this.SetMethod("GET").RequestStream()
+	if err != nil {
+		if offset > 0 && this.StatusCode == 416 {
+			// Range Not Satisfiable: 文件已经下载完毕
+			if progress != nil {
+				progress(offset, offset)
+			}
+			return nil
+		}
+		return err
+	}
+	defer body.Close()
+
+	start, total, ok := parseContentRange(this.responseHeader.Get("Content-Range"))
+	flag := os.O_CREATE | os.O_WRONLY
+	if this.StatusCode == 206 {
+		if !ok || start != offset {
+			// 返回的数据与断点不符，追加到文件中会破坏文件内容
+			this.err = fmt.Errorf("服务端返回的 Content-Range 与断点不符: %v, offset=%v", this.responseHeader.Get("Content-Range"), offset)
+			return this.err
+		}
+		flag |= os.O_APPEND
+	} else {
+		// 服务端不支持 Range，重新下载
+		flag |= os.O_TRUNC
+		offset = 0
+	}
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if total < 0 {
+		if n, err := strconv.ParseInt(this.responseHeader.Get("Content-Length"), 10, 64); err == nil {
+			total = offset + n
+		}
+	}
+
+	var w io.Writer = file
+	if progress != nil {
+		progress(offset, total)
+		w = &progressWriter{w: file, written: offset, total: total, f: progress}
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		if this.err == nil {
+			this.err = err
+		}
+		return err
+	}
+	return nil
+}
+
+// 从 Content-Range（bytes 0-99/1000）中解析起始位置以及文件总长度（总长度未知时为 -1）
+func parseContentRange(s string) (int64, int64, bool) {
+	total := int64(-1)
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, total, false
+	}
+	s = s[len("bytes "):]
+	if pos := strings.LastIndex(s, "/"); pos != -1 {
+		if n, err := strconv.ParseInt(s[pos+1:], 10, 64); err == nil {
+			total = n
+		}
+		s = s[:pos]
+	}
+	pos := strings.Index(s, "-")
+	if pos == -1 {
+		return 0, total, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(s[:pos]), 10, 64)
+	if err != nil {
+		return 0, total, false
+	}
+	return start, total, true
+}
+
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	total   int64
+	f       ProgressFunc
+}
+
+func (this *progressWriter) Write(p []byte) (int, error) {
+	n, err := this.w.Write(p)
+	if n > 0 {
+		this.written += int64(n)
+		this.f(this.written, this.total)
+	}
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
@@ -0,0 +1,185 @@
+package httpClient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_RequestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), 1024*1024))
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL)
+	defer client.Close()
+	body, err := client.GetStream()
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	defer body.Close()
+	if n, err := io.Copy(ioutil.Discard, body); err != nil || n != 1024*1024 {
+		t.Errorf("assert faild, n=%v, err=%v", n, err)
+	}
+	if len(client.ResponseData()) != 0 {
+		t.Errorf("stream response should not be buffered")
+	}
+}
+
+func TestClient_MultipartRetry(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "httpClient")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	ioutil.WriteFile(path, []byte("file content"), 0644)
+
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1024); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		data, _ := ioutil.ReadAll(file)
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(r.FormValue("name") + "|" + header.Filename + "|" + string(data)))
+	}))
+	defer server.Close()
+
+	policy := NewFixedRetry(2, time.Millisecond)
+	policy.RetryNonIdempotent = true
+	client := Open().SetUrl(server.URL).SetMultipart(NewMultipart().AddField("name", "abc").AddFile("file", path)).SetRetryPolicy(policy).Post()
+	defer client.Close()
+	if err := client.Error(); err != nil {
+		t.Errorf("error occured: %v", err)
+	} else if text := client.ResponseText(); text != "abc|a.txt|file content" {
+		t.Errorf("assert faild: %v", text)
+	}
+}
+
+func TestClient_MultipartShortCircuit(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 1024*1024)
+	m := NewMultipart().AddReader("file", "a.bin", func() (io.Reader, error) { return bytes.NewReader(content), nil })
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		// 拦截器只读取了一部分 body，没有关闭就直接返回
+		client := Open().SetUrl("http://127.0.0.1:1/never").SetMultipart(m).AddInterceptor(func(c *Client, req *http.Request, next RoundTripFunc) (*http.Response, error) {
+			req.Body.Read(make([]byte, 10))
+			return &http.Response{StatusCode: 200}, nil
+		}).Post()
+		if err := client.Error(); err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+		client.Close()
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before+2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > before+2 {
+		t.Errorf("goroutine leaked: %v -> %v", before, runtime.NumGoroutine())
+	}
+}
+
+func TestClient_BodyFunc(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.WriteHeader(500)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	client := Open().SetUrl(server.URL).SetBodyFunc(func() (io.Reader, error) {
+		return strings.NewReader("hello"), nil
+	}).SetRetry(2, time.Millisecond).SetMethod("PUT").Request()
+	defer client.Close()
+	if client.Error() != nil || client.ResponseText() != "hello" || client.Attempts() != 2 {
+		t.Errorf("assert faild, err=%v, text=%v, attempts=%v", client.Error(), client.ResponseText(), client.Attempts())
+	}
+}
+
+func TestClient_DownloadToFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.bin", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "httpClient")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.bin")
+
+	// 模拟已经下载了一部分
+	ioutil.WriteFile(path, content[:30000], 0644)
+
+	var lastWritten, lastTotal int64
+	client := Open().SetUrl(server.URL)
+	defer client.Close()
+	err := client.DownloadToFile(path, func(written, total int64) {
+		lastWritten, lastTotal = written, total
+	})
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+		return
+	}
+	if client.StatusCode != 206 {
+		t.Errorf("expect 206, got %v", client.StatusCode)
+	}
+	if data, _ := ioutil.ReadFile(path); !bytes.Equal(data, content) {
+		t.Errorf("file content not match, len=%v", len(data))
+	}
+	if lastWritten != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Errorf("progress not match, written=%v, total=%v", lastWritten, lastTotal)
+	}
+
+	// 已经下载完毕
+	client2 := Open().SetUrl(server.URL)
+	defer client2.Close()
+	if err := client2.DownloadToFile(path, nil); err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+}
+
+func TestClient_DownloadToFileRangeMismatch(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 无论请求的 Range 是什么，总是从头返回
+		w.Header().Set("Content-Range", "bytes 0-999/1000")
+		w.WriteHeader(206)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "httpClient")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.bin")
+	ioutil.WriteFile(path, content[:300], 0644)
+
+	client := Open().SetUrl(server.URL)
+	defer client.Close()
+	if err := client.DownloadToFile(path, nil); err == nil || !strings.Contains(err.Error(), "Content-Range") {
+		t.Errorf("assert faild: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); !bytes.Equal(data, content[:300]) {
+		t.Errorf("file should not be modified, len=%v", len(data))
+	}
+}
@@ -83,7 +83,7 @@ func New(name string, capacity int, handler HandlerFunc, opt ...*Options) Queue
 		worker:   realOpt.Worker,
 		jobChan:  make(chan interface{}, capacity+64),
 		handler:  handler,
-		status:   Status_Created,
+		status:   int32(Status_Created),
 		counter:  realOpt.Counter,
 	}
 	return this
@@ -91,6 +91,7 @@ func New(name string, capacity int, handler HandlerFunc, opt ...*Options) Queue
 
 type queueImpl struct {
 	runningWorker int32 // 需要 atomic 原子操作，放在结构体开头保证内存地址对齐
+	status        int32 // 需要 atomic 原子操作，通过 getStatus/setStatus 读写
 	id            int32
 	name          string
 	capacity      int
@@ -99,8 +100,7 @@ type queueImpl struct {
 	handler       func(job interface{}, t time.Time)
 	waitGroup     sync.WaitGroup
 	stopChan      []chan bool
-	currJob       *jobWrap
-	status        Status
+	pauseLock     sync.RWMutex // 执行任务时持有读锁，Pause 通过获取写锁等待正在执行的任务完成
 	statusLock    sync.Mutex
 	counter       timeRoundedCounter.TimeRoundedCounter
 }
@@ -180,28 +180,32 @@ func (this *queueImpl) Size() int { return len(this.jobChan) }
 func (this *queueImpl) Worker() int { return len(this.stopChan) }
 
 // 获取当前是否正在运行（已启动、尚未停止）
-func (this *queueImpl) Status() Status { return this.status }
+func (this *queueImpl) Status() Status { return this.getStatus() }
+
+func (this *queueImpl) getStatus() Status { return Status(atomic.LoadInt32(&this.status)) }
+
+func (this *queueImpl) setStatus(v Status) { atomic.StoreInt32(&this.status, int32(v)) }
 
 // 获取创建队列时指定的计数器
 func (this *queueImpl) Counter() timeRoundedCounter.TimeRoundedCounter { return this.counter }
 
 // 入队。返回是否成功，以及队列状态。如果队列处于 Status_Stopping|Status_Stopped ，或者队列已满，都会导致入队失败。
 func (this *queueImpl) Add(job interface{}) (bool, Status) {
-	if this.status == Status_Stopping || this.status == Status_Stopped {
-		return false, this.status
+	if status := this.getStatus(); status == Status_Stopping || status == Status_Stopped {
+		return false, status
 	}
 	if len(this.jobChan) >= this.capacity {
-		return false, this.status
+		return false, this.getStatus()
 	}
 	this.waitGroup.Add(1)
 	this.jobChan <- &jobWrap{job: job, time: time.Now()}
 	if this.counter != nil {
 		this.counter.Add(1)
 	}
-	if this.status == Status_Created {
+	if this.getStatus() == Status_Created {
 		setActive(this.id, this)
 	}
-	return true, this.status
+	return true, this.getStatus()
 }
 
 // 启动处理程序
@@ -209,14 +213,14 @@ func (this *queueImpl) Start() error {
 	this.statusLock.Lock()
 	defer this.statusLock.Unlock()
 
-	if this.status == Status_Running || this.status == Status_Pause {
+	if status := this.getStatus(); status == Status_Running || status == Status_Pause {
 		return nil
-	} else if this.status != Status_Created {
-		return fmt.Errorf("队列已停止或正在停止[%v]", this.status.String())
+	} else if status != Status_Created {
+		return fmt.Errorf("队列已停止或正在停止[%v]", status.String())
 	}
 
 	this.ensureWorker()
-	this.status = Status_Running
+	this.setStatus(Status_Running)
 	setActive(this.id, this)
 
 	return nil
@@ -225,11 +229,11 @@ func (this *queueImpl) Start() error {
 func (this *queueImpl) Pause() error {
 	this.statusLock.Lock()
 	defer this.statusLock.Unlock()
-	if this.status == Status_Running {
-		for this.currJob != nil {
-			time.Sleep(20 * time.Millisecond)
-		}
-		this.status = Status_Pause
+	if this.getStatus() == Status_Running {
+		// 等待正在执行的任务完成
+		this.pauseLock.Lock()
+		this.setStatus(Status_Pause)
+		this.pauseLock.Unlock()
 	}
 	return nil
 }
@@ -237,8 +241,8 @@ func (this *queueImpl) Pause() error {
 func (this *queueImpl) Resume() error {
 	this.statusLock.Lock()
 	defer this.statusLock.Unlock()
-	if this.status == Status_Pause {
-		this.status = Status_Running
+	if this.getStatus() == Status_Pause {
+		this.setStatus(Status_Running)
 	}
 	return nil
 }
@@ -248,7 +252,7 @@ func (this *queueImpl) SetWorker(worker int) error {
 	defer this.statusLock.Unlock()
 
 	this.worker = worker
-	if this.status == Status_Running || this.status == Status_Pause {
+	if this.getStatus() == Status_Running || this.getStatus() == Status_Pause {
 		this.ensureWorker()
 	}
 
@@ -257,26 +261,29 @@ func (this *queueImpl) SetWorker(worker int) error {
 
 func (this *queueImpl) ensureWorker() {
 	for i, n := len(this.stopChan), mathUtil.MaxInt(1, this.worker); i < n; i++ {
-		this.stopChan = append(this.stopChan, make(chan bool))
+		stop := make(chan bool)
+		this.stopChan = append(this.stopChan, stop)
 		atomic.AddInt32(&this.runningWorker, 1)
-		go func(i int) {
+		go func() {
 			for {
 				select {
 				case v := <-this.jobChan:
 					if v != nil {
-						for this.status == Status_Pause {
+						this.pauseLock.RLock()
+						for this.getStatus() == Status_Pause {
+							this.pauseLock.RUnlock()
 							time.Sleep(20 * time.Millisecond)
+							this.pauseLock.RLock()
 						}
 						if w, ok := v.(*jobWrap); ok {
-							this.currJob = w
 							this.handler(w.job, w.time)
 							this.waitGroup.Add(-1)
-							this.currJob = nil
 						}
-					} else if this.status == Status_Stopped {
+						this.pauseLock.RUnlock()
+					} else if this.getStatus() == Status_Stopped {
 						return
 					}
-				case v := <-this.stopChan[i]:
+				case v := <-stop:
 					if v && atomic.AddInt32(&this.runningWorker, -1) == 0 {
 						go func() {
 							this.waitGroup.Wait()
@@ -285,12 +292,12 @@ func (this *queueImpl) ensureWorker() {
 					}
 				}
 			}
-		}(i)
+		}()
 	}
 }
 
 func (this *queueImpl) doStop() {
-	this.status = Status_Stopped
+	this.setStatus(Status_Stopped)
 	for _, c := range this.stopChan {
 		close(c)
 	}
@@ -303,12 +310,10 @@ func (this *queueImpl) Stop(timeout time.Duration) (bool, Status) {
 	this.statusLock.Lock()
 	defer this.statusLock.Unlock()
 
-	if this.status == Status_Created || this.status == Status_Stopped {
-		return true, this.status
-	}
-
-	if this.status == Status_Running || this.status == Status_Pause {
-		this.status = Status_Stopping
+	if status := this.getStatus(); status == Status_Created || status == Status_Stopped {
+		return true, status
+	} else if status == Status_Running || status == Status_Pause {
+		this.setStatus(Status_Stopping)
 		go func() {
 			for _, c := range this.stopChan {
 				c <- true
@@ -318,15 +323,15 @@ func (this *queueImpl) Stop(timeout time.Duration) (bool, Status) {
 
 	sleep := 10 * time.Microsecond
 	if timeout > 0 {
-		for end := time.Now().Add(timeout); this.status != Status_Stopped && time.Now().Before(end); {
+		for end := time.Now().Add(timeout); this.getStatus() != Status_Stopped && time.Now().Before(end); {
 			time.Sleep(sleep)
 		}
-		return this.status == Status_Stopped, this.status
+		return this.getStatus() == Status_Stopped, this.getStatus()
 	} else {
-		for this.status != Status_Stopped {
+		for this.getStatus() != Status_Stopped {
 			time.Sleep(sleep)
 		}
-		return true, this.status
+		return true, this.getStatus()
 	}
 }
 
@@ -334,7 +339,7 @@ func (this *queueImpl) Abort() {
 	this.statusLock.Lock()
 	defer this.statusLock.Unlock()
 
-	if this.status == Status_Created || this.status == Status_Stopped {
+	if this.getStatus() == Status_Created || this.getStatus() == Status_Stopped {
 		return
 	}
 
@@ -1,6 +1,7 @@
 package chanTaskQueue
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -60,3 +61,37 @@ func TestQueueImpl_Pause(t *testing.T) {
 		t.Error("assert faild")
 	}
 }
+
+func TestQueueImpl_PauseWorkers(t *testing.T) {
+	var running, handled int32
+	queue := New("test", 1000, func(job interface{}, t time.Time) {
+		atomic.AddInt32(&running, 1)
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&handled, 1)
+	}, &Options{Worker: 4})
+	queue.Start()
+	for i := 0; i < 100; i++ {
+		queue.Add(i)
+	}
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		queue.Pause()
+		// 暂停之后没有正在执行的任务，也不会开始新的任务
+		n := atomic.LoadInt32(&handled)
+		if v := atomic.LoadInt32(&running); v != 0 {
+			t.Errorf("assert faild: %v jobs are running after pause", v)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if v := atomic.LoadInt32(&handled); v != n {
+			t.Errorf("assert faild: %v != %v", v, n)
+		}
+		queue.Resume()
+	}
+
+	queue.Stop(0)
+	if v := atomic.LoadInt32(&handled); v != 100 {
+		t.Errorf("assert faild: %v", v)
+	}
+}